@@ -0,0 +1,106 @@
+package catalog_service
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/PoojaSrinivasan18/catalog-service/common"
+	"github.com/PoojaSrinivasan18/catalog-service/database"
+	"github.com/PoojaSrinivasan18/catalog-service/model"
+
+	"github.com/apex/log"
+	"github.com/gin-gonic/gin"
+)
+
+// BulkDeleteProducts soft-deletes every product in a category, or every product in a SKU list, in one
+// transaction. SKUs are matched case-insensitively and any that match no product are reported back.
+func BulkDeleteProducts(c *gin.Context) {
+	var req model.BulkDeleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("JSON binding error: %v", err)
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid request", err.Error())
+		return
+	}
+
+	category := normalizeCategory(req.Category)
+	if (category == "") == (len(req.Skus) == 0) {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Provide either category or skus")
+		return
+	}
+
+	// Key requested SKUs by their lowercase form so misses can be reported in the caller's spelling
+	requested := make(map[string]string, len(req.Skus))
+	for _, sku := range req.Skus {
+		sku = strings.TrimSpace(sku)
+		if _, seen := requested[strings.ToLower(sku)]; sku != "" && !seen {
+			requested[strings.ToLower(sku)] = sku
+		}
+	}
+	if category == "" && len(requested) == 0 {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "skus must contain at least one SKU")
+		return
+	}
+
+	tx := database.GetDB().Begin()
+
+	query := tx.Model(&model.ProductModel{})
+	if category != "" {
+		query = query.Where("category = ?", category)
+	} else {
+		lowered := make([]string, 0, len(requested))
+		for sku := range requested {
+			lowered = append(lowered, sku)
+		}
+		query = query.Where("LOWER(sku) IN ?", lowered)
+	}
+
+	var products []model.ProductModel
+	if err := query.Find(&products).Error; err != nil {
+		tx.Rollback()
+		log.Errorf("DB query error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to fetch products")
+		return
+	}
+
+	productIds := make([]int, 0, len(products))
+	for _, product := range products {
+		productIds = append(productIds, product.ProductId)
+		delete(requested, strings.ToLower(product.Sku))
+	}
+
+	if len(productIds) > 0 {
+		if err := tx.Delete(&model.ProductModel{}, productIds).Error; err != nil {
+			tx.Rollback()
+			log.Errorf("Bulk delete failed: %v", err)
+			common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to delete products")
+			return
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Errorf("Bulk delete commit failed: %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to delete products")
+		return
+	}
+
+	for _, productId := range productIds {
+		productDetailCache.invalidate(productId)
+	}
+
+	// Report unmatched SKUs in request order, once each
+	notFound := make([]string, 0, len(requested))
+	for _, sku := range req.Skus {
+		key := strings.ToLower(strings.TrimSpace(sku))
+		if original, ok := requested[key]; ok {
+			notFound = append(notFound, original)
+			delete(requested, key)
+		}
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"message":     "Products deleted",
+		"deleted":     len(productIds),
+		"product_ids": productIds,
+		"not_found":   notFound,
+	})
+}
@@ -0,0 +1,250 @@
+package catalog_service
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/PoojaSrinivasan18/catalog-service/common"
+	"github.com/PoojaSrinivasan18/catalog-service/database"
+	"github.com/PoojaSrinivasan18/catalog-service/model"
+
+	"github.com/apex/log"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// bundleComponentView is one component as returned by GetProductBundle
+type bundleComponentView struct {
+	ProductId int     `json:"product_id"`
+	Sku       string  `json:"sku"`
+	Name      string  `json:"name"`
+	Price     float64 `json:"price"`
+	IsActive  bool    `json:"is_active"`
+	Quantity  int     `json:"quantity"`
+}
+
+// loadBundleComponents returns a bundle's components joined with their products. Soft-deleted
+// components are left out, so callers compare against the raw row count to spot them.
+func loadBundleComponents(db *gorm.DB, bundleId int) ([]bundleComponentView, int64, error) {
+	var rows int64
+	if err := db.Model(&model.ProductBundleItem{}).Where("bundle_id = ?", bundleId).Count(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var components []bundleComponentView
+	err := db.Table("product_bundle_items AS b").
+		Select("p.product_id, p.sku, p.name, p.price, p.is_active, b.quantity").
+		Joins("JOIN product_models AS p ON p.product_id = b.component_id AND p.deleted_at IS NULL").
+		Where("b.bundle_id = ?", bundleId).
+		Order("p.product_id asc").
+		Scan(&components).Error
+	return components, rows, err
+}
+
+// GetProductBundle lists the components of a bundle product; standalone products get a 404
+func GetProductBundle(c *gin.Context) {
+	productId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Product ID must be a valid integer")
+		return
+	}
+
+	db := database.GetDB()
+
+	var product model.ProductModel
+	if err := db.First(&product, "product_id = ?", productId).Error; err != nil {
+		common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "Invalid product ID")
+		return
+	}
+
+	components, rows, err := loadBundleComponents(db, productId)
+	if err != nil {
+		log.Errorf("DB bundle query error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to fetch bundle")
+		return
+	}
+	if rows == 0 {
+		common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "Product is not a bundle")
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"product_id":         product.ProductId,
+		"sku":                product.Sku,
+		"name":               product.Name,
+		"components":         components,
+		"count":              len(components),
+		"missing_components": rows - int64(len(components)),
+	})
+}
+
+// SetProductBundle replaces the components of a product, turning it into a bundle. Components must be
+// existing standalone products, so bundles never nest.
+func SetProductBundle(c *gin.Context) {
+	productId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Product ID must be a valid integer")
+		return
+	}
+
+	var req model.SetBundleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("JSON binding error: %v", err)
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid request", err.Error())
+		return
+	}
+
+	componentIds := make([]int, 0, len(req.Components))
+	seen := make(map[int]bool, len(req.Components))
+	for _, component := range req.Components {
+		if component.ProductId == productId {
+			common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "A bundle cannot contain itself")
+			return
+		}
+		if seen[component.ProductId] {
+			common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Each component may appear only once; use quantity instead")
+			return
+		}
+		seen[component.ProductId] = true
+		componentIds = append(componentIds, component.ProductId)
+	}
+
+	tx := database.GetDB().Begin()
+
+	var product model.ProductModel
+	if err := tx.First(&product, "product_id = ?", productId).Error; err != nil {
+		tx.Rollback()
+		common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "Invalid product ID")
+		return
+	}
+
+	if len(componentIds) > 0 {
+		var found int64
+		if err := tx.Model(&model.ProductModel{}).Where("product_id IN ?", componentIds).Count(&found).Error; err != nil {
+			tx.Rollback()
+			log.Errorf("DB query error %v", err)
+			common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to update bundle")
+			return
+		}
+		if found != int64(len(componentIds)) {
+			tx.Rollback()
+			common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Every component must be an existing product")
+			return
+		}
+
+		// Keep bundles one level deep: components can't be bundles, and a component can't become one
+		var nested int64
+		if err := tx.Model(&model.ProductBundleItem{}).
+			Where("bundle_id IN ? OR component_id = ?", componentIds, productId).
+			Count(&nested).Error; err != nil {
+			tx.Rollback()
+			log.Errorf("DB query error %v", err)
+			common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to update bundle")
+			return
+		}
+		if nested > 0 {
+			tx.Rollback()
+			common.RespondError(c, http.StatusConflict, common.CodeConflict, "Bundles cannot contain other bundles or be part of one")
+			return
+		}
+	}
+
+	if err := tx.Where("bundle_id = ?", productId).Delete(&model.ProductBundleItem{}).Error; err != nil {
+		tx.Rollback()
+		log.Errorf("DB delete error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to update bundle")
+		return
+	}
+
+	if len(req.Components) > 0 {
+		items := make([]model.ProductBundleItem, 0, len(req.Components))
+		for _, component := range req.Components {
+			items = append(items, model.ProductBundleItem{
+				BundleId:    productId,
+				ComponentId: component.ProductId,
+				Quantity:    component.Quantity,
+			})
+		}
+		if err := tx.Create(&items).Error; err != nil {
+			tx.Rollback()
+			log.Errorf("DB insert error %v", err)
+			common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to update bundle")
+			return
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Errorf("DB commit error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to update bundle")
+		return
+	}
+
+	log.Infof("Product %d bundle set to %d components", productId, len(req.Components))
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"product_id": productId,
+		"components": req.Components,
+		"is_bundle":  len(req.Components) > 0,
+	})
+}
+
+// ReserveBundle reserves quantity units of a bundle for an order. Each component is reserved at its
+// per-bundle quantity times the bundle quantity in a single inventory batch, so either all are held or none.
+func ReserveBundle(c *gin.Context) {
+	productId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Product ID must be a valid integer")
+		return
+	}
+
+	var req model.ReserveBundleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("JSON binding error: %v", err)
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid request", err.Error())
+		return
+	}
+
+	db := database.GetDB()
+
+	var product model.ProductModel
+	if err := db.First(&product, "product_id = ?", productId).Error; err != nil {
+		common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "Invalid product ID")
+		return
+	}
+	if !product.IsActive {
+		common.RespondError(c, http.StatusConflict, common.CodeConflict, "Bundle is not active")
+		return
+	}
+
+	components, rows, err := loadBundleComponents(db, productId)
+	if err != nil {
+		log.Errorf("DB bundle query error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to fetch bundle")
+		return
+	}
+	if rows == 0 {
+		common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "Product is not a bundle")
+		return
+	}
+	if int64(len(components)) != rows {
+		common.RespondError(c, http.StatusConflict, common.CodeConflict, "Bundle references a deleted product")
+		return
+	}
+
+	items := make([]gin.H, 0, len(components))
+	for _, component := range components {
+		items = append(items, gin.H{
+			"product_id": component.ProductId,
+			"quantity":   component.Quantity * req.Quantity,
+		})
+	}
+
+	status, body, err := reserveBundleComponents(items, req, common.RequestId(c), c.GetHeader("Authorization"))
+	if err != nil {
+		log.Errorf("Bundle %d reservation for order %s failed: %v", productId, req.OrderId, err)
+		common.RespondError(c, http.StatusBadGateway, common.CodeUpstream, "Inventory service unavailable")
+		return
+	}
+
+	// Inventory's reply (including its insufficient-stock details) is relayed unchanged
+	c.Data(status, "application/json; charset=utf-8", body)
+}
@@ -1,17 +1,28 @@
 package catalog_service
 
 import (
+	"encoding/csv"
+	"errors"
 	"net/http"
 	"strconv"
-	"time"
+	"strings"
+	"unicode"
 
+	"github.com/PoojaSrinivasan18/catalog-service/common"
 	"github.com/PoojaSrinivasan18/catalog-service/database"
 	"github.com/PoojaSrinivasan18/catalog-service/model"
 
 	"github.com/apex/log"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// productWithStock is a product merged with its available inventory, when known
+type productWithStock struct {
+	model.ProductModel
+	TotalAvailable *int `json:"total_available,omitempty"`
+}
+
 func GetProductById(c *gin.Context) {
 	// Try to get ID from URL parameter first, then query parameter
 	productIdStr := c.Param("id")
@@ -22,34 +33,94 @@ func GetProductById(c *gin.Context) {
 	productId, err := strconv.Atoi(productIdStr)
 	if err != nil {
 		log.Errorf("Invalid product ID: %v", err)
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID", "message": "Product ID must be a valid integer"})
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Product ID must be a valid integer")
 		return
 	}
 
-	var existingProductDetail model.ProductModel
-	database := database.GetDB()
+	existingProductDetail, cached := productDetailCache.get(productId)
+	if !cached {
+		t := database.GetDB().Where("product_id=?", productId).First(&existingProductDetail)
+		if t.Error != nil {
+			log.Errorf("DB query error %v", t.Error)
+			if errors.Is(t.Error, gorm.ErrRecordNotFound) {
+				common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "Product not found")
+				return
+			}
+			common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to fetch product")
+			return
+		}
+		productDetailCache.set(existingProductDetail)
+	}
 
-	t := database.Where("product_id=?", productId).First(&existingProductDetail)
-	if t.Error != nil {
-		log.Errorf("DB query error %v", t.Error)
-		c.IndentedJSON(http.StatusNotFound, gin.H{"message": t.Error})
+	if c.Query("with_stock") != "true" {
+		c.IndentedJSON(http.StatusOK, existingProductDetail)
 		return
 	}
 
-	c.IndentedJSON(http.StatusOK, existingProductDetail)
+	// Stock is best-effort: the product still renders when inventory is unreachable
+	response := productWithStock{ProductModel: existingProductDetail}
+	available, err := fetchAvailableStock(existingProductDetail.ProductId, common.RequestId(c))
+	if err != nil {
+		log.Warnf("Inventory lookup failed for product %d: %v", existingProductDetail.ProductId, err)
+	} else {
+		response.TotalAvailable = &available
+	}
+
+	c.IndentedJSON(http.StatusOK, response)
 }
+
+// GetProductBySku returns the product with the given SKU, matched case-insensitively
+func GetProductBySku(c *gin.Context) {
+	sku := strings.TrimSpace(c.Param("sku"))
+	if sku == "" {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "SKU is required")
+		return
+	}
+
+	// Soft-deleted products are excluded by GORM's DeletedAt scope
+	var product model.ProductModel
+	if err := database.GetDB().Where("LOWER(sku) = ?", strings.ToLower(sku)).First(&product).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.RespondErrorWithDetails(c, http.StatusNotFound, common.CodeNotFound, "Product not found", gin.H{"sku": sku})
+			return
+		}
+		log.Errorf("DB query error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to fetch product")
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, product)
+}
+
+// GetAllProducts lists products a page at a time; without page/limit it returns the first page
 func GetAllProducts(c *gin.Context) {
-	var products []model.ProductModel
-	db := database.GetDB()
+	limit, offset := common.Paginate(c)
+
+	db, cancel := database.RequestDB(c)
+	defer cancel()
+
+	var total int64
+	if err := db.Model(&model.ProductModel{}).Count(&total).Error; err != nil {
+		if database.RespondIfTimedOut(c, err) {
+			return
+		}
+		log.Errorf("DB count error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to list products")
+		return
+	}
 
-	t := db.Find(&products)
+	var products []model.ProductModel
+	t := db.Order("product_id asc").Limit(limit).Offset(offset).Find(&products)
 	if t.Error != nil {
+		if database.RespondIfTimedOut(c, t.Error) {
+			return
+		}
 		log.Errorf("DB query error %v", t.Error)
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": t.Error.Error()})
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to list products")
 		return
 	}
 
-	c.IndentedJSON(http.StatusOK, products)
+	c.IndentedJSON(http.StatusOK, common.NewPageResponse(products, total, limit, offset).WithLegacyKey("products", len(products)))
 }
 
 func AddProduct(c *gin.Context) {
@@ -57,116 +128,279 @@ func AddProduct(c *gin.Context) {
 	err := c.ShouldBind(&productModel)
 	if err != nil {
 		log.Errorf("FORM binding error %v", err.Error())
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err})
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, err.Error())
 		return
 	}
 
-	tx := database.GetDB().Create(&productModel)
+	productModel.Sku = strings.TrimSpace(productModel.Sku)
+	productModel.Name = strings.TrimSpace(productModel.Name)
+	if productModel.Sku == "" || productModel.Name == "" {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Product sku and name are required")
+		return
+	}
+	if productModel.Price <= 0 {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Product price must be greater than zero")
+		return
+	}
+	productModel.Category = normalizeCategory(productModel.Category)
+
+	// The key may come in the body or the Idempotency-Key header
+	idempotencyKey := ""
+	if productModel.IdempotencyKey != nil {
+		idempotencyKey = strings.TrimSpace(*productModel.IdempotencyKey)
+	}
+	idempotencyKey = common.ResolveIdempotencyKey(c, idempotencyKey)
+	productModel.IdempotencyKey = nil
+	if idempotencyKey != "" {
+		productModel.IdempotencyKey = &idempotencyKey
+	}
+
+	db := database.GetDB()
+
+	// A retried create returns the product made by the first attempt
+	if idempotencyKey != "" && respondWithExistingProduct(c, db, idempotencyKey) {
+		return
+	}
+
+	tx := db.Create(&productModel)
+	if errors.Is(tx.Error, gorm.ErrDuplicatedKey) {
+		// A concurrent retry may have won the race on the idempotency key
+		if idempotencyKey != "" && respondWithExistingProduct(c, db, idempotencyKey) {
+			return
+		}
+		common.RespondError(c, http.StatusConflict, common.CodeConflict, "A product with SKU "+productModel.Sku+" already exists")
+		return
+	}
 	if tx.Error != nil {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Error adding product"})
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Error adding product")
 		return
 	}
 
 	c.IndentedJSON(http.StatusOK, productModel)
 }
-func DeleteProduct(c *gin.Context) {
-	productId, err := strconv.Atoi(c.Query("productId"))
+
+// respondWithExistingProduct replies with the product created under the idempotency key, if any
+func respondWithExistingProduct(c *gin.Context, db *gorm.DB, idempotencyKey string) bool {
+	var existingProduct model.ProductModel
+	if err := db.Where("idempotency_key = ?", idempotencyKey).First(&existingProduct).Error; err != nil {
+		return false
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"message":    "Product already created",
+		"product":    existingProduct,
+		"idempotent": true,
+	})
+	return true
+}
+
+// normalizeCategory trims a category and title-cases each word so "shoes" and "Shoes" match
+func normalizeCategory(category string) string {
+	words := strings.Fields(category)
+	for i, word := range words {
+		runes := []rune(strings.ToLower(word))
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " ")
+}
+
+// ImportRowError describes why a CSV row was skipped during import
+type ImportRowError struct {
+	Row    int    `json:"row"`
+	Reason string `json:"reason"`
+}
+
+// ImportProducts bulk-inserts products from an uploaded CSV, skipping invalid or duplicate rows
+func ImportProducts(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
 	if err != nil {
-		log.Errorf("Invalid product ID: %v", err)
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Invalid product ID"})
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Upload the CSV as multipart form field 'file'")
 		return
 	}
 
-	var existingProductDetail model.ProductModel
-	database := database.GetDB()
+	f, err := fileHeader.Open()
+	if err != nil {
+		log.Errorf("Cannot open uploaded file: %v", err)
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Cannot open uploaded file")
+		return
+	}
+	defer f.Close()
 
-	t := database.Where("product_id=?", productId).First(&existingProductDetail)
-	if t.Error != nil {
-		log.Errorf("DB query error %v", t.Error)
-		c.IndentedJSON(http.StatusNotFound, gin.H{"message": t.Error})
+	r := csv.NewReader(f)
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		log.Errorf("CSV read error: %v", err)
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "CSV read error", err.Error())
+		return
+	}
+	if len(records) < 2 {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "CSV contains no data")
 		return
 	}
 
-	tx := database.Model(&existingProductDetail).Delete(existingProductDetail)
-	if tx.Error != nil {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Error saving product data"})
+	header := records[0]
+	idx := make(map[string]int)
+	for i, h := range header {
+		idx[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+
+	// field returns the trimmed value of a column, or "" when the column is missing
+	field := func(row []string, name string) string {
+		if v, ok := idx[name]; ok && v < len(row) {
+			return strings.TrimSpace(row[v])
+		}
+		return ""
+	}
+
+	db := database.GetDB()
+
+	// Load existing SKUs up front so duplicates are skipped instead of failing the transaction.
+	// Soft-deleted products still hold their SKU in the unique index, so include them.
+	var existingSkus []string
+	if err := db.Unscoped().Model(&model.ProductModel{}).Pluck("sku", &existingSkus).Error; err != nil {
+		log.Errorf("DB query error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to load existing products")
 		return
 	}
+	seenSkus := make(map[string]bool, len(existingSkus))
+	for _, sku := range existingSkus {
+		seenSkus[sku] = true
+	}
 
-	c.IndentedJSON(http.StatusOK, "Product deleted successfully")
-}
+	var products []model.ProductModel
+	rowErrors := []ImportRowError{}
 
-/*
-	func UpdateProduct(c *gin.Context) {
-		var product model.ProductModel
-		database := database.GetDB()
+	for ri := 1; ri < len(records); ri++ {
+		row := records[ri]
+		rowNum := ri + 1
 
-		// Bind JSON body
-		if err := c.BindJSON(&product); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
-			return
+		sku := field(row, "sku")
+		if sku == "" {
+			rowErrors = append(rowErrors, ImportRowError{Row: rowNum, Reason: "missing sku"})
+			continue
+		}
+		if seenSkus[sku] {
+			rowErrors = append(rowErrors, ImportRowError{Row: rowNum, Reason: "duplicate sku " + sku})
+			continue
 		}
 
-		// Validate product_id
-		if product.ProductId == 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"message": "Product ID is required"})
-			return
+		name := field(row, "name")
+		if name == "" {
+			rowErrors = append(rowErrors, ImportRowError{Row: rowNum, Reason: "missing name"})
+			continue
+		}
+
+		price, perr := strconv.ParseFloat(field(row, "price"), 64)
+		if perr != nil || price <= 0 {
+			rowErrors = append(rowErrors, ImportRowError{Row: rowNum, Reason: "invalid price"})
+			continue
 		}
 
-		var existingProduct model.ProductModel
-		// Try to find the product by product_id
-		if err := database.First(&existingProduct, "product_id = ?", product.ProductId).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"message": "Invalid product ID"})
+		isActive := true
+		if v := field(row, "is_active"); v != "" {
+			parsed, berr := strconv.ParseBool(v)
+			if berr != nil {
+				rowErrors = append(rowErrors, ImportRowError{Row: rowNum, Reason: "invalid is_active"})
+				continue
+			}
+			isActive = parsed
+		}
+
+		seenSkus[sku] = true
+		products = append(products, model.ProductModel{
+			Sku:         sku,
+			Name:        name,
+			Category:    normalizeCategory(field(row, "category")),
+			Price:       price,
+			Description: field(row, "description"),
+			IsActive:    isActive,
+		})
+	}
+
+	if len(products) > 0 {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return tx.Create(&products).Error
+		})
+		if err != nil {
+			log.Errorf("DB insert error during import: %v", err)
+			common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to import products")
 			return
 		}
+	}
 
-		// Update fields
-		existingProduct.Sku = product.Sku
-		existingProduct.Price = product.Price
-		existingProduct.Name = product.Name
-		existingProduct.Category = product.Category
-		existingProduct.IsActive = product.IsActive
-		existingProduct.Description = product.Description
-		existingProduct.UpdatedAt = time.Now()
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"inserted": len(products),
+		"skipped":  len(rowErrors),
+		"errors":   rowErrors,
+	})
+}
+
+func DeleteProduct(c *gin.Context) {
+	productId, err := strconv.Atoi(c.Query("productId"))
+	if err != nil {
+		log.Errorf("Invalid product ID: %v", err)
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid product ID")
+		return
+	}
 
-		// Save updated product
-		if err := database.Save(&existingProduct).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to update product"})
+	var existingProductDetail model.ProductModel
+	database := database.GetDB()
+
+	t := database.Where("product_id=?", productId).First(&existingProductDetail)
+	if t.Error != nil {
+		log.Errorf("DB query error %v", t.Error)
+		if errors.Is(t.Error, gorm.ErrRecordNotFound) {
+			common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "Product not found")
 			return
 		}
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to fetch product")
+		return
+	}
 
-		c.JSON(http.StatusOK, gin.H{
-			"message": "Product updated successfully",
-			"product": existingProduct,
-		})
+	tx := database.Model(&existingProductDetail).Delete(existingProductDetail)
+	if tx.Error != nil {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Error saving product data")
+		return
 	}
-*/
+	productDetailCache.invalidate(productId)
+
+	c.IndentedJSON(http.StatusOK, "Product deleted successfully")
+}
+
 func UpdateProduct(c *gin.Context) {
-	var product model.ProductModel
+	var product model.UpdateProductRequest
 	database := database.GetDB()
 
 	// Bind JSON body
-	if err := c.BindJSON(&product); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
+	if err := c.ShouldBindJSON(&product); err != nil {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid request body")
 		return
 	}
 
 	// Validate product_id
 	if product.ProductId == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Product ID is required"})
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Product ID is required")
 		return
 	}
 
 	var existingProduct model.ProductModel
 	// Try to find the product by product_id
 	if err := database.First(&existingProduct, "product_id = ?", product.ProductId).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"message": "Invalid product ID"})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "Invalid product ID")
+			return
+		}
+		log.Errorf("DB product lookup error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to update product")
 		return
 	}
 	// Update fields
 	if product.Sku != "" {
 		existingProduct.Sku = product.Sku
 	}
+	oldPrice := existingProduct.Price
 	if product.Price != 0.0 {
 		existingProduct.Price = product.Price
 	}
@@ -175,22 +409,40 @@ func UpdateProduct(c *gin.Context) {
 	}
 
 	if product.Category != "" {
-		existingProduct.Category = product.Category
+		existingProduct.Category = normalizeCategory(product.Category)
 	}
-	if !product.IsActive {
-		existingProduct.IsActive = product.IsActive
+	// Only touch IsActive when the client sent it, so both activation and deactivation work
+	if product.IsActive != nil {
+		existingProduct.IsActive = *product.IsActive
 	}
 	if product.Description != "" {
 		existingProduct.Description = product.Description
 	}
 
-	existingProduct.UpdatedAt = time.Now()
-
-	// Save updated product
-	if err := database.Save(&existingProduct).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to update product"})
+	// Save updated product, recording the old price in the same transaction when it changed
+	err := database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&existingProduct).Error; err != nil {
+			return err
+		}
+		if existingProduct.Price == oldPrice {
+			return nil
+		}
+		return tx.Create(&model.ProductPriceHistory{
+			ProductId: existingProduct.ProductId,
+			OldPrice:  oldPrice,
+			NewPrice:  existingProduct.Price,
+			ChangedAt: existingProduct.UpdatedAt,
+		}).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			common.RespondError(c, http.StatusConflict, common.CodeConflict, "A product with SKU "+existingProduct.Sku+" already exists")
+			return
+		}
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to update product")
 		return
 	}
+	productDetailCache.invalidate(existingProduct.ProductId)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Product updated successfully",
@@ -198,11 +450,136 @@ func UpdateProduct(c *gin.Context) {
 	})
 }
 
+// GetPriceHistory lists a product's price changes, oldest first
+func GetPriceHistory(c *gin.Context) {
+	productId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Product ID must be a valid integer")
+		return
+	}
+
+	db := database.GetDB()
+
+	var product model.ProductModel
+	if err := db.Select("product_id").First(&product, "product_id = ?", productId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "Invalid product ID")
+			return
+		}
+		log.Errorf("DB product lookup error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to fetch price history")
+		return
+	}
+
+	var history []model.ProductPriceHistory
+	if err := db.Where("product_id = ?", productId).Order("changed_at asc, id asc").Find(&history).Error; err != nil {
+		log.Errorf("DB price history error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to fetch price history")
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"product_id": productId,
+		"history":    history,
+		"count":      len(history),
+	})
+}
+
+// ActivateProduct marks a product active so it shows up in default searches again
+func ActivateProduct(c *gin.Context) {
+	setProductActive(c, true)
+}
+
+// DeactivateProduct hides a product from default searches; it stays fetchable by ID
+func DeactivateProduct(c *gin.Context) {
+	setProductActive(c, false)
+}
+
+// setProductActive flips IsActive for the product in the :id path parameter
+func setProductActive(c *gin.Context, active bool) {
+	productId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Product ID must be a valid integer")
+		return
+	}
+
+	db := database.GetDB()
+
+	var product model.ProductModel
+	if err := db.First(&product, "product_id = ?", productId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "Invalid product ID")
+			return
+		}
+		log.Errorf("DB product lookup error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to update product")
+		return
+	}
+
+	// Update writes false explicitly and stamps updated_at, unlike a struct-based update
+	if err := db.Model(&product).Update("is_active", active).Error; err != nil {
+		log.Errorf("DB update error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to update product")
+		return
+	}
+	productDetailCache.invalidate(productId)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product status updated successfully",
+		"product": product,
+	})
+}
+
+// CategoryCount is a product category with the number of products in it
+type CategoryCount struct {
+	Category string `json:"category"`
+	Count    int64  `json:"count"`
+}
+
+// GetCategories lists the distinct non-empty categories with their product counts
+func GetCategories(c *gin.Context) {
+	var categories []CategoryCount
+	db, cancel := database.RequestDB(c)
+	defer cancel()
+
+	query := db.Model(&model.ProductModel{}).
+		Select("category, COUNT(*) AS count").
+		Where("category <> ''")
+
+	if c.Query("active_only") == "true" {
+		query = query.Where("is_active = ?", true)
+	}
+
+	if err := query.Group("category").Order("category ASC").Scan(&categories).Error; err != nil {
+		if database.RespondIfTimedOut(c, err) {
+			return
+		}
+		log.Errorf("DB query error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to list categories")
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"categories": categories,
+		"count":      len(categories),
+	})
+}
+
+// productSortColumns maps the accepted sort_by values to their database columns
+var productSortColumns = map[string]string{
+	"price":      "price",
+	"name":       "name",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
 func SearchProducts(c *gin.Context) {
 	var products []model.ProductModel
-	db := database.GetDB()
+	db, cancel := database.RequestDB(c)
+	defer cancel()
 
 	// Get query parameters
+	q := strings.ToLower(strings.TrimSpace(c.Query("q")))
 	name := c.Query("name")
 	category := c.Query("category")
 	minPrice := c.Query("min_price")
@@ -212,49 +589,105 @@ func SearchProducts(c *gin.Context) {
 	// Build the query
 	query := db.Model(&model.ProductModel{})
 
+	// Free-text search matches any of name, description or SKU; the other filters still AND with it
+	if q != "" {
+		term := "%" + q + "%"
+		query = query.Where("(LOWER(name) LIKE ? OR LOWER(description) LIKE ? OR LOWER(sku) LIKE ?)", term, term, term)
+	}
 	if name != "" {
 		query = query.Where("LOWER(name) LIKE ?", "%"+name+"%")
 	}
-	if category != "" {
+	// With include_subcategories the category is matched exactly along with everything beneath it;
+	// otherwise it stays a flat substring match
+	if category != "" && c.Query("include_subcategories") == "true" {
+		parents, err := loadCategoryParents(db)
+		if err != nil {
+			if database.RespondIfTimedOut(c, err) {
+				return
+			}
+			log.Errorf("DB category query error %v", err)
+			common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Database search failed")
+			return
+		}
+		query = query.Where("category IN ?", categoryDescendants(parents, normalizeCategory(category)))
+	} else if category != "" {
 		query = query.Where("LOWER(category) LIKE ?", "%"+category+"%")
 	}
+	var minValue, maxValue float64
 	if minPrice != "" {
-		query = query.Where("price >= ?", minPrice)
+		parsed, err := strconv.ParseFloat(minPrice, 64)
+		if err != nil {
+			common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "min_price must be a number")
+			return
+		}
+		minValue = parsed
+		query = query.Where("price >= ?", minValue)
 	}
 	if maxPrice != "" {
-		query = query.Where("price <= ?", maxPrice)
+		parsed, err := strconv.ParseFloat(maxPrice, 64)
+		if err != nil {
+			common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "max_price must be a number")
+			return
+		}
+		maxValue = parsed
+		query = query.Where("price <= ?", maxValue)
 	}
-	if isActive == "true" {
-		query = query.Where("is_active = ?", true)
-	} else if isActive == "false" {
+	if minPrice != "" && maxPrice != "" && minValue > maxValue {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "min_price cannot be greater than max_price")
+		return
+	}
+	// Inactive products are hidden unless asked for; is_active=all returns both
+	switch isActive {
+	case "false":
 		query = query.Where("is_active = ?", false)
+	case "all":
+	default:
+		query = query.Where("is_active = ?", true)
 	}
 
-	// Execute query with pagination
-	limit := 50 // Default limit
-	if l := c.Query("limit"); l != "" {
-		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
-			limit = parsed
+	// Apply a whitelisted sort so the column name never comes from user input
+	sortColumn := "product_id"
+	if sortBy := c.Query("sort_by"); sortBy != "" {
+		column, ok := productSortColumns[strings.ToLower(sortBy)]
+		if !ok {
+			common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "sort_by must be one of price, name, created_at, updated_at")
+			return
 		}
+		sortColumn = column
 	}
 
-	offset := 0
-	if o := c.Query("offset"); o != "" {
-		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
-			offset = parsed
+	sortOrder := "asc"
+	if order := strings.ToLower(c.DefaultQuery("order", "asc")); order == "desc" {
+		sortOrder = "desc"
+	} else if order != "asc" {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "order must be asc or desc")
+		return
+	}
+
+	// Execute query with pagination
+	limit, offset := common.Paginate(c)
+
+	// Count on a copy of the filtered query so the total matches the result set
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		if database.RespondIfTimedOut(c, err) {
+			return
 		}
+		log.Errorf("DB count error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Database search failed")
+		return
 	}
 
-	if err := query.Limit(limit).Offset(offset).Find(&products).Error; err != nil {
+	if err := query.Order(sortColumn + " " + sortOrder).Limit(limit).Offset(offset).Find(&products).Error; err != nil {
+		if database.RespondIfTimedOut(c, err) {
+			return
+		}
 		log.Errorf("DB search error %v", err)
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Database search failed"})
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Database search failed")
 		return
 	}
 
-	c.IndentedJSON(http.StatusOK, gin.H{
-		"products": products,
-		"count":    len(products),
-		"limit":    limit,
-		"offset":   offset,
-	})
+	response := common.NewPageResponse(products, total, limit, offset).WithLegacyKey("products", len(products))
+	response["offset"] = offset
+	c.IndentedJSON(http.StatusOK, response)
 }
@@ -0,0 +1,181 @@
+package catalog_service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/PoojaSrinivasan18/catalog-service/database/dbtest"
+	"github.com/PoojaSrinivasan18/catalog-service/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sendJSON serves one JSON request through the router and returns the recorded response
+func sendJSON(router *gin.Engine, method string, path string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestUpdateProductIsActive(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := dbtest.Open(t, &model.ProductModel{}, &model.ProductPriceHistory{})
+	router := gin.New()
+	router.PATCH("/v1/products/:id", UpdateProduct)
+
+	tests := []struct {
+		name     string
+		initial  bool
+		isActive string // the is_active member of the body, or empty to omit it
+		want     bool
+	}{
+		{"deactivate", true, `,"is_active":false`, false},
+		{"activate", false, `,"is_active":true`, true},
+		{"omitted keeps active", true, "", true},
+		{"omitted keeps inactive", false, "", false},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product := model.ProductModel{Sku: "SKU-" + strconv.Itoa(i), Name: "Widget", Price: 10, IsActive: tt.initial}
+			if err := db.Create(&product).Error; err != nil {
+				t.Fatalf("create product: %v", err)
+			}
+
+			id := strconv.Itoa(product.ProductId)
+			w := sendJSON(router, http.MethodPatch, "/v1/products/"+id, `{"product_id":`+id+`,"description":"updated"`+tt.isActive+`}`)
+			if w.Code != http.StatusOK {
+				t.Fatalf("update: got %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+			}
+
+			var stored model.ProductModel
+			if err := db.First(&stored, "product_id = ?", product.ProductId).Error; err != nil {
+				t.Fatalf("load product: %v", err)
+			}
+			if stored.IsActive != tt.want {
+				t.Fatalf("is_active is %v, want %v", stored.IsActive, tt.want)
+			}
+			if stored.Description != "updated" {
+				t.Fatalf("description is %q, want %q", stored.Description, "updated")
+			}
+		})
+	}
+}
+
+func TestAddProductDuplicateSku(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := dbtest.Open(t, &model.ProductModel{}, &model.ProductPriceHistory{})
+	router := gin.New()
+	router.POST("/v1/products", AddProduct)
+
+	if err := db.Create(&model.ProductModel{Sku: "SKU-1", Name: "Widget", Price: 10, IsActive: true}).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	deleted := model.ProductModel{Sku: "SKU-2", Name: "Gadget", Price: 10}
+	if err := db.Create(&deleted).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	if err := db.Delete(&deleted).Error; err != nil {
+		t.Fatalf("soft-delete product: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		sku    string
+		status int
+	}{
+		{"new sku", "SKU-3", http.StatusOK},
+		{"existing sku", "SKU-1", http.StatusConflict},
+		{"existing sku with padding", "  SKU-1 ", http.StatusConflict},
+		{"soft-deleted sku", "SKU-2", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := sendJSON(router, http.MethodPost, "/v1/products", `{"sku":"`+tt.sku+`","name":"Widget","price":12.5}`)
+			if w.Code != tt.status {
+				t.Fatalf("add %q: got %d, want %d: %s", tt.sku, w.Code, tt.status, w.Body.String())
+			}
+			if tt.status == http.StatusConflict && !strings.Contains(w.Body.String(), "CONFLICT") {
+				t.Fatalf("add %q: want a CONFLICT error code: %s", tt.sku, w.Body.String())
+			}
+		})
+	}
+
+	var count int64
+	db.Unscoped().Model(&model.ProductModel{}).Count(&count)
+	if count != 3 {
+		t.Fatalf("got %d products, want 3", count)
+	}
+}
+
+func TestProductLookupErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.PATCH("/v1/products/:id", UpdateProduct)
+	router.GET("/v1/products/:id/price-history", GetPriceHistory)
+	router.POST("/v1/products/:id/activate", ActivateProduct)
+
+	requests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPatch, "/v1/products/999", `{"product_id":999,"name":"Widget"}`},
+		{http.MethodGet, "/v1/products/999/price-history", ""},
+		{http.MethodPost, "/v1/products/999/activate", ""},
+	}
+
+	// A missing product is a 404, but a failing database is a 500 rather than a claim the product does not exist
+	for _, closed := range []bool{false, true} {
+		name, want := "missing product", http.StatusNotFound
+		if closed {
+			name, want = "database unavailable", http.StatusInternalServerError
+		}
+		t.Run(name, func(t *testing.T) {
+			db := dbtest.Open(t, &model.ProductModel{}, &model.ProductPriceHistory{})
+			if closed {
+				sqlDB, err := db.DB()
+				if err != nil {
+					t.Fatalf("test database handle: %v", err)
+				}
+				sqlDB.Close()
+			}
+			for _, r := range requests {
+				if w := sendJSON(router, r.method, r.path, r.body); w.Code != want {
+					t.Errorf("%s %s: got %d, want %d: %s", r.method, r.path, w.Code, want, w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestAddProductIdempotencyKeyHeader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := dbtest.Open(t, &model.ProductModel{}, &model.ProductPriceHistory{})
+	router := gin.New()
+	router.POST("/v1/products", AddProduct)
+
+	for attempt, wantIdempotent := range []bool{false, true} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/products", strings.NewReader(`{"sku":"SKU-1","name":"Widget","price":12.5}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "create-1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("attempt %d: got %d, want %d: %s", attempt+1, w.Code, http.StatusOK, w.Body.String())
+		}
+		if idempotent := strings.Contains(w.Body.String(), `"idempotent": true`); idempotent != wantIdempotent {
+			t.Fatalf("attempt %d: idempotent replay is %v, want %v: %s", attempt+1, idempotent, wantIdempotent, w.Body.String())
+		}
+	}
+
+	var count int64
+	db.Model(&model.ProductModel{}).Where("idempotency_key = ?", "create-1").Count(&count)
+	if count != 1 {
+		t.Fatalf("got %d products for the idempotency key, want 1", count)
+	}
+}
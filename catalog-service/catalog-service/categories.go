@@ -0,0 +1,178 @@
+package catalog_service
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/PoojaSrinivasan18/catalog-service/common"
+	"github.com/PoojaSrinivasan18/catalog-service/database"
+	"github.com/PoojaSrinivasan18/catalog-service/model"
+
+	"github.com/apex/log"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CategoryNode is one category in the tree with its product counts and subcategories
+type CategoryNode struct {
+	Category   string          `json:"category"`
+	Count      int64           `json:"count"`       // products directly in this category
+	TotalCount int64           `json:"total_count"` // products in this category and every descendant
+	Children   []*CategoryNode `json:"children"`
+}
+
+// loadCategoryParents returns each category's parent, keyed by category name
+func loadCategoryParents(db *gorm.DB) (map[string]string, error) {
+	var rows []model.CategoryModel
+	if err := db.Where("parent_category <> ''").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	parents := make(map[string]string, len(rows))
+	for _, row := range rows {
+		parents[row.Name] = row.ParentCategory
+	}
+	return parents, nil
+}
+
+// categoryDescendants returns root followed by every category beneath it
+func categoryDescendants(parents map[string]string, root string) []string {
+	children := make(map[string][]string, len(parents))
+	for category, parent := range parents {
+		children[parent] = append(children[parent], category)
+	}
+
+	descendants := []string{root}
+	for i := 0; i < len(descendants); i++ {
+		descendants = append(descendants, children[descendants[i]]...)
+	}
+	return descendants
+}
+
+// SetCategoryParent moves a category under another one, or back to the top level with an empty parent.
+// A category can't be placed under itself or one of its own descendants.
+func SetCategoryParent(c *gin.Context) {
+	category := normalizeCategory(c.Param("category"))
+	if category == "" {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Category must not be empty")
+		return
+	}
+
+	var req model.SetCategoryParentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("JSON binding error: %v", err)
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid request", err.Error())
+		return
+	}
+	parent := normalizeCategory(req.ParentCategory)
+
+	db := database.GetDB()
+
+	if parent != "" {
+		parents, err := loadCategoryParents(db)
+		if err != nil {
+			log.Errorf("DB category query error %v", err)
+			common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to update category")
+			return
+		}
+		for _, descendant := range categoryDescendants(parents, category) {
+			if descendant == parent {
+				common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "A category cannot be placed under itself or its subcategories", gin.H{
+					"category":        category,
+					"parent_category": parent,
+				})
+				return
+			}
+		}
+	}
+
+	row := model.CategoryModel{Name: category, ParentCategory: parent}
+	if err := db.Save(&row).Error; err != nil {
+		log.Errorf("DB category save error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to update category")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Category updated successfully",
+		"category": row,
+	})
+}
+
+// GetCategoryTree returns the categories nested under their parents with direct and total product counts.
+// Parents with no products of their own still appear; ?active_only=true counts only active products.
+func GetCategoryTree(c *gin.Context) {
+	db := database.GetDB()
+
+	query := db.Model(&model.ProductModel{}).
+		Select("category, COUNT(*) AS count").
+		Where("category <> ''")
+	if c.Query("active_only") == "true" {
+		query = query.Where("is_active = ?", true)
+	}
+
+	var counts []CategoryCount
+	if err := query.Group("category").Scan(&counts).Error; err != nil {
+		log.Errorf("DB query error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to list categories")
+		return
+	}
+
+	parents, err := loadCategoryParents(db)
+	if err != nil {
+		log.Errorf("DB category query error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to list categories")
+		return
+	}
+
+	nodes := make(map[string]*CategoryNode)
+	node := func(category string) *CategoryNode {
+		if nodes[category] == nil {
+			nodes[category] = &CategoryNode{Category: category, Children: []*CategoryNode{}}
+		}
+		return nodes[category]
+	}
+	for _, count := range counts {
+		node(count.Category).Count = count.Count
+	}
+	for category, parent := range parents {
+		node(category)
+		node(parent)
+	}
+
+	roots := make([]*CategoryNode, 0)
+	for category, n := range nodes {
+		if parent, ok := parents[category]; ok {
+			nodes[parent].Children = append(nodes[parent].Children, n)
+		} else {
+			roots = append(roots, n)
+		}
+	}
+
+	sortCategoryNodes(roots)
+	for _, root := range roots {
+		sumCategoryCounts(root)
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"categories": roots,
+		"count":      len(nodes),
+	})
+}
+
+// sortCategoryNodes orders nodes, and recursively their children, by name
+func sortCategoryNodes(nodes []*CategoryNode) {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Category < nodes[j].Category })
+	for _, n := range nodes {
+		sortCategoryNodes(n.Children)
+	}
+}
+
+// sumCategoryCounts fills in TotalCount for a node and everything beneath it
+func sumCategoryCounts(n *CategoryNode) int64 {
+	n.TotalCount = n.Count
+	for _, child := range n.Children {
+		n.TotalCount += sumCategoryCounts(child)
+	}
+	return n.TotalCount
+}
@@ -0,0 +1,110 @@
+package catalog_service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PoojaSrinivasan18/catalog-service/common"
+	"github.com/PoojaSrinivasan18/catalog-service/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// inventoryClient is used for outbound calls to the inventory service
+var inventoryClient = &http.Client{Timeout: 3 * time.Second}
+
+// inventoryAvailability is the subset of the inventory availability response used by catalog
+type inventoryAvailability struct {
+	ProductId      int `json:"product_id"`
+	TotalAvailable int `json:"total_available"`
+}
+
+// fetchAvailableStock asks the inventory service how many units of a product are available.
+// The request ID is forwarded so both services log the call under the same ID.
+func fetchAvailableStock(productId int, requestId string) (int, error) {
+	baseUrl := inventoryServiceUrl()
+	if baseUrl == "" {
+		return 0, fmt.Errorf("inventory service URL is not configured")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v1/inventory/availability/%d", strings.TrimRight(baseUrl, "/"), productId), nil)
+	if err != nil {
+		return 0, err
+	}
+	if requestId != "" {
+		req.Header.Set(common.RequestIdHeader, requestId)
+	}
+
+	resp, err := inventoryClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("inventory service responded with status %d", resp.StatusCode)
+	}
+
+	var availability inventoryAvailability
+	if err := json.NewDecoder(resp.Body).Decode(&availability); err != nil {
+		return 0, err
+	}
+	return availability.TotalAvailable, nil
+}
+
+// reserveBundleComponents reserves every component line through the inventory batch-reserve endpoint so
+// they are held atomically. The inventory status and body are returned as-is for the caller to relay.
+func reserveBundleComponents(items []gin.H, req model.ReserveBundleRequest, requestId, authorization string) (int, []byte, error) {
+	baseUrl := inventoryServiceUrl()
+	if baseUrl == "" {
+		return 0, nil, fmt.Errorf("inventory service URL is not configured")
+	}
+
+	payload, err := json.Marshal(gin.H{
+		"items":           items,
+		"order_id":        req.OrderId,
+		"idempotency_key": req.IdempotencyKey,
+		"region":          req.Region,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, strings.TrimRight(baseUrl, "/")+"/v1/inventory/reserve/batch", bytes.NewReader(payload))
+	if err != nil {
+		return 0, nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if requestId != "" {
+		httpReq.Header.Set(common.RequestIdHeader, requestId)
+	}
+	// Forwarded so inventory records the caller, not catalog, as the reservation's creator
+	if authorization != "" {
+		httpReq.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := inventoryClient.Do(httpReq)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, body, nil
+}
+
+// inventoryServiceUrl returns the configured inventory service base URL
+func inventoryServiceUrl() string {
+	if config := common.GetConfig(); config != nil {
+		return config.Inventory.Url
+	}
+	return ""
+}
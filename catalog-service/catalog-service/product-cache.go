@@ -0,0 +1,122 @@
+package catalog_service
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/PoojaSrinivasan18/catalog-service/common"
+	"github.com/PoojaSrinivasan18/catalog-service/database"
+	"github.com/PoojaSrinivasan18/catalog-service/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultProductCacheTtl = 30 * time.Second
+	// productCachePruneSize is the entry count at which a write also drops expired entries
+	productCachePruneSize = 10000
+)
+
+type productCacheEntry struct {
+	product   model.ProductModel
+	expiresAt time.Time
+}
+
+// productCache is a TTL cache of product details keyed by product ID, safe for concurrent use
+type productCache struct {
+	mu      sync.RWMutex
+	entries map[int]productCacheEntry
+	hits    atomic.Int64
+	misses  atomic.Int64
+}
+
+var productDetailCache = &productCache{entries: map[int]productCacheEntry{}}
+
+// get returns the cached product if present and not expired
+func (pc *productCache) get(productId int) (model.ProductModel, bool) {
+	pc.mu.RLock()
+	entry, ok := pc.entries[productId]
+	pc.mu.RUnlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		pc.misses.Add(1)
+		return model.ProductModel{}, false
+	}
+	pc.hits.Add(1)
+	return entry.product, true
+}
+
+// set caches a product for the configured TTL
+func (pc *productCache) set(product model.ProductModel) {
+	now := time.Now()
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if len(pc.entries) >= productCachePruneSize {
+		for id, entry := range pc.entries {
+			if now.After(entry.expiresAt) {
+				delete(pc.entries, id)
+			}
+		}
+	}
+	pc.entries[product.ProductId] = productCacheEntry{product: product, expiresAt: now.Add(productCacheTtl())}
+}
+
+// invalidate drops a product so the next read goes to the database
+func (pc *productCache) invalidate(productId int) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	delete(pc.entries, productId)
+}
+
+// clear drops every cached product
+func (pc *productCache) clear() {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	pc.entries = map[int]productCacheEntry{}
+}
+
+// productCacheTtl returns the configured product cache TTL
+func productCacheTtl() time.Duration {
+	if config := common.GetConfig(); config != nil && config.Cache.ProductTtl > 0 {
+		return config.Cache.ProductTtl
+	}
+	return defaultProductCacheTtl
+}
+
+// GetMetrics exposes catalog counters in the Prometheus text format
+func GetMetrics(c *gin.Context) {
+	productDetailCache.mu.RLock()
+	size := len(productDetailCache.entries)
+	productDetailCache.mu.RUnlock()
+
+	body := fmt.Sprintf(`# HELP catalog_product_cache_hits_total Product detail reads served from the cache.
+# TYPE catalog_product_cache_hits_total counter
+catalog_product_cache_hits_total %d
+# HELP catalog_product_cache_misses_total Product detail reads that went to the database.
+# TYPE catalog_product_cache_misses_total counter
+catalog_product_cache_misses_total %d
+# HELP catalog_product_cache_entries Products currently held in the cache.
+# TYPE catalog_product_cache_entries gauge
+catalog_product_cache_entries %d
+`, productDetailCache.hits.Load(), productDetailCache.misses.Load(), size)
+
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(body))
+}
+
+// resetCatalogTables truncates every catalog table; it refuses unless ALLOW_RESET is set
+var resetCatalogTables = database.ResetTables("catalog", &model.ProductModel{}, &model.ProductPriceHistory{}, &model.ProductBundleItem{}, &model.CategoryModel{})
+
+// ResetCatalog wipes the catalog for e2e runs and drops the cached products with it
+func ResetCatalog(c *gin.Context) {
+	resetCatalogTables(c)
+	if c.Writer.Status() == http.StatusOK {
+		productDetailCache.clear()
+	}
+}
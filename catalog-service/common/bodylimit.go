@@ -0,0 +1,73 @@
+package common
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Body size caps used when request.maxbodybytes or request.maxuploadbytes is unset
+const (
+	DefaultMaxBodyBytes   = 1 << 20
+	DefaultMaxUploadBytes = 10 << 20
+)
+
+// LimitRequestBody answers 413 for request bodies over maxBytes, or over maxUploadBytes for multipart
+// uploads. Other bodies are read up front so an oversized payload is refused before any handler
+// binds a truncated copy of it.
+func LimitRequestBody(maxBytes, maxUploadBytes int64) gin.HandlerFunc {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBodyBytes
+	}
+	if maxUploadBytes <= 0 {
+		maxUploadBytes = DefaultMaxUploadBytes
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		limit := maxBytes
+		if c.ContentType() == "multipart/form-data" {
+			limit = maxUploadBytes
+		}
+		if c.Request.ContentLength > limit {
+			abortTooLarge(c, limit)
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		if limit == maxUploadBytes && limit != maxBytes {
+			// Uploads are streamed; a lying Content-Length still stops at the cap
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				abortTooLarge(c, limit)
+				return
+			}
+			AbortWithError(c, http.StatusBadRequest, CodeInvalidRequest, "Unable to read request body")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Next()
+	}
+}
+
+// abortTooLarge stops the request with 413 and the limit it exceeded
+func abortTooLarge(c *gin.Context, limit int64) {
+	c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, ErrorResponse{
+		Code:    CodePayloadTooLarge,
+		Message: "Request body is too large",
+		Details: gin.H{"max_bytes": limit},
+	})
+}
@@ -1,6 +1,8 @@
 package common
 
 import (
+	"time"
+
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
@@ -8,7 +10,12 @@ import (
 var Config *Configuration
 
 type Configuration struct {
-	Database DatabaseConfiguration
+	Database  DatabaseConfiguration
+	Inventory InventoryConfiguration
+	Cors      CorsConfiguration
+	Cache     CacheConfiguration
+	Reset     ResetConfiguration
+	Request   RequestConfiguration
 }
 
 type DatabaseConfiguration struct {
@@ -21,6 +28,52 @@ type DatabaseConfiguration struct {
 	MaxLifetime  int
 	MaxOpenConns int
 	MaxIdleConns int
+	// ConnectAttempts and ConnectBackoff control the startup retry; the backoff doubles after each failure
+	ConnectAttempts int
+	ConnectBackoff  time.Duration
+	// QueryTimeout bounds the request-scoped queries of the heavy read endpoints
+	QueryTimeout time.Duration
+}
+
+type InventoryConfiguration struct {
+	Url string
+}
+
+// CacheConfiguration controls in-memory caching of product reads
+type CacheConfiguration struct {
+	ProductTtl time.Duration
+}
+
+// ResetConfiguration guards POST /v1/admin/reset, which wipes every table; only enable it for e2e runs
+type ResetConfiguration struct {
+	Allowed bool
+}
+
+// RequestConfiguration caps request body sizes in bytes; MaxUploadBytes applies to multipart uploads
+type RequestConfiguration struct {
+	MaxBodyBytes   int64
+	MaxUploadBytes int64
+}
+
+// CorsConfiguration lists the browser origins allowed to call the API; empty denies all
+type CorsConfiguration struct {
+	AllowedOrigins []string
+}
+
+// databaseEnvBindings maps each database setting to the env vars that override it; the first one set wins
+var databaseEnvBindings = map[string][]string{
+	"database.driver":          {"DB_DRIVER"},
+	"database.host":            {"DB_HOST"},
+	"database.port":            {"DB_PORT"},
+	"database.dbname":          {"DB_NAME"},
+	"database.username":        {"DB_USER"},
+	"database.password":        {"DB_PASSWORD", "APP_DB_PASSWORD"},
+	"database.maxlifetime":     {"DB_MAX_LIFETIME"},
+	"database.maxopenconns":    {"DB_MAX_OPEN_CONNS"},
+	"database.maxidleconns":    {"DB_MAX_IDLE_CONNS"},
+	"database.connectattempts": {"DB_CONNECT_ATTEMPTS"},
+	"database.connectbackoff":  {"DB_CONNECT_BACKOFF"},
+	"database.querytimeout":    {"DB_QUERY_TIMEOUT"},
 }
 
 func ConfigSetup(configPath string) error {
@@ -34,6 +87,26 @@ func ConfigSetup(configPath string) error {
 		return err
 	}
 
+	// Database settings resolve as env var, then config file, then default
+	for key, envs := range databaseEnvBindings {
+		_ = viper.BindEnv(append([]string{key}, envs...)...)
+	}
+	viper.SetDefault("database.driver", "postgres")
+	viper.SetDefault("database.host", "postgres_main")
+	viper.SetDefault("database.port", "5432")
+
+	// Comma-separated origins, e.g. "https://admin.example.com,http://localhost:5173"
+	_ = viper.BindEnv("cors.allowedorigins", "CORS_ALLOWED_ORIGINS")
+
+	_ = viper.BindEnv("request.maxbodybytes", "MAX_BODY_BYTES")
+	_ = viper.BindEnv("request.maxuploadbytes", "MAX_UPLOAD_BYTES")
+
+	// Allow the inventory service location to be overridden per environment
+	_ = viper.BindEnv("inventory.url", "INVENTORY_SERVICE_URL")
+
+	// Table resets stay off unless an e2e environment opts in
+	_ = viper.BindEnv("reset.allowed", "ALLOW_RESET")
+
 	err := viper.Unmarshal(&configuration)
 	if err != nil {
 		log.Fatalf("Unable to decode into struct, %v", err)
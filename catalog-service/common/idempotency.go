@@ -0,0 +1,27 @@
+package common
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// IdempotencyKeyHeader lets clients send the idempotency key as a header instead of in the body
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// ResolveIdempotencyKey returns the body key when present, falling back to the Idempotency-Key header.
+// A header that disagrees with the body is ignored with a warning.
+func ResolveIdempotencyKey(c *gin.Context, bodyKey string) string {
+	headerKey := strings.TrimSpace(c.GetHeader(IdempotencyKeyHeader))
+	if bodyKey == "" {
+		return headerKey
+	}
+	if headerKey != "" && headerKey != bodyKey {
+		log.WithFields(log.Fields{
+			"request_id": RequestId(c),
+			"path":       c.FullPath(),
+		}).Warn("Idempotency-Key header differs from the body idempotency_key; using the body key")
+	}
+	return bodyKey
+}
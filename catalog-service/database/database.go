@@ -17,6 +17,9 @@ var (
 	err  error
 )
 
+// defaultHost is used when neither the config file nor DB_HOST names a database host
+const defaultHost = "postgres_main"
+
 type Repository struct {
 	Database *gorm.DB
 }
@@ -38,7 +41,8 @@ func SetupDB(configuration *common.Configuration) error {
 	if host != "" {
 		log.Infof("Host IP is %v", host)
 	} else {
-		log.Error("Host is Empty in Env Variable")
+		host = defaultHost
+		log.Warnf("Host is Empty in config and DB_HOST, falling back to %v", host)
 	}
 
 	// data source name
@@ -52,9 +56,9 @@ func SetupDB(configuration *common.Configuration) error {
 	)
 
 	if driver == "postgres" { // Postgres DB
-		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		db, err = openWithRetry(postgres.Open(dsn), &gorm.Config{TranslateError: true}, configuration.Database)
 		if err != nil {
-			log.Errorf("db err: ", err)
+			return err
 		}
 	}
 
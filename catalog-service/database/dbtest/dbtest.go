@@ -0,0 +1,37 @@
+// Package dbtest gives tests a throwaway database in place of Postgres
+package dbtest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PoojaSrinivasan18/catalog-service/database"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Open points database.Repo at a fresh in-memory SQLite database with the given models migrated, and
+// restores it when the test ends. SQLite ignores SELECT ... FOR UPDATE, so row locking is not exercised.
+func Open(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	db, err := gorm.Open(sqlite.Open("file:"+name+"?mode=memory&cache=shared"), &gorm.Config{TranslateError: true})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+
+	previous := database.Repo.Database
+	database.Repo.Database = db
+	t.Cleanup(func() {
+		database.Repo.Database = previous
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"github.com/PoojaSrinivasan18/catalog-service/auth"
 	catalog_service "github.com/PoojaSrinivasan18/catalog-service/catalog-service"
 	"github.com/PoojaSrinivasan18/catalog-service/common"
 	"github.com/PoojaSrinivasan18/catalog-service/database"
@@ -8,12 +9,19 @@ import (
 
 	"github.com/apex/log"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 func main() {
 
 	log.Info("Starting Catalog Service")
 
+	// Admin-only routes verify customer-service tokens, so the shared secret is required
+	if err := auth.LoadSecret(); err != nil {
+		log.Errorf("JWT secret setup failed: %v", err)
+		return
+	}
+
 	err := common.ConfigSetup("config/dbconfig.yaml")
 	if err != nil {
 		log.Errorf("ConfigSetup failed: %v", err)
@@ -33,29 +41,53 @@ func main() {
 
 	log.Infof(" Running AutoMigrate...")
 	database.GetDB().Exec("SET search_path TO product;")
-	err = database.GetDB().AutoMigrate(&model.ProductModel{})
+	err = database.GetDB().AutoMigrate(&model.ProductModel{}, &model.ProductPriceHistory{}, &model.ProductBundleItem{}, &model.CategoryModel{})
 	if err != nil {
 		log.Errorf("AutoMigrate failed: %v", err)
 	} else {
 		log.Infof(" Migration successful!")
 	}
 
-	router := gin.Default()
+	// JSON bodies with unknown fields are rejected, so a misspelled field is a 400 rather than silently ignored
+	binding.EnableDecoderDisallowUnknownFields = true
+
+	// RequestLogger replaces gin's default access log with one structured line per request
+	router := gin.New()
+	router.Use(gin.Recovery(), common.RequestLogger())
+	router.Use(common.CORS(configuration.Cors.AllowedOrigins))
+	router.Use(common.LimitRequestBody(configuration.Request.MaxBodyBytes, configuration.Request.MaxUploadBytes))
+
+	// Health checks: /live for liveness, /ready (and /health) for readiness, which pings the database
+	router.GET("/live", database.LivenessCheck("catalog"))
+	router.GET("/ready", database.ReadinessCheck("catalog"))
+	router.GET("/health", database.ReadinessCheck("catalog"))
 
-	// Add health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "healthy", "service": "catalog"})
-	})
+	// Connection pool stats, for diagnosing pool exhaustion under load
+	router.GET("/debug/dbstats", database.DbStats("catalog"))
 
 	// API versioning with /v1
 	v1 := router.Group("/v1")
 	{
+		v1.POST("/admin/reset", catalog_service.ResetCatalog)
 		v1.GET("/products/:id", catalog_service.GetProductById)
+		v1.GET("/products/:id/price-history", catalog_service.GetPriceHistory)
+		v1.GET("/products/:id/bundle", catalog_service.GetProductBundle)
+		v1.PUT("/products/:id/bundle", catalog_service.SetProductBundle)
+		v1.POST("/products/:id/bundle/reserve", catalog_service.ReserveBundle)
+		v1.GET("/products/sku/:sku", catalog_service.GetProductBySku)
 		v1.GET("/products", catalog_service.GetAllProducts)
 		v1.POST("/products", catalog_service.AddProduct)
-		v1.DELETE("/products/:id", catalog_service.DeleteProduct)
+		v1.POST("/products/import", catalog_service.ImportProducts)
+		v1.DELETE("/products/:id", auth.AuthRequired(), auth.RequireRole(auth.RoleAdmin), catalog_service.DeleteProduct)
+		v1.POST("/products/bulk-delete", auth.AuthRequired(), auth.RequireRole(auth.RoleAdmin), catalog_service.BulkDeleteProducts)
 		v1.PATCH("/products/:id", catalog_service.UpdateProduct)
+		v1.POST("/products/:id/activate", catalog_service.ActivateProduct)
+		v1.POST("/products/:id/deactivate", catalog_service.DeactivateProduct)
 		v1.GET("/products/search", catalog_service.SearchProducts)
+		v1.GET("/products/categories", catalog_service.GetCategories)
+		v1.GET("/products/categories/tree", catalog_service.GetCategoryTree)
+		v1.PUT("/products/categories/:category", catalog_service.SetCategoryParent)
+		v1.GET("/metrics", catalog_service.GetMetrics)
 	}
 
 	router.Run(":3000")
@@ -1,15 +1,88 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type ProductModel struct {
-	ProductId   int       `json:"product_id" gorm:"primaryKey;autoIncrement:true"`
-	Sku         string    `json:"sku"`
-	Price       float64   `json:"price"`
-	Name        string    `json:"name"`
-	Category    string    `json:"category"`
-	IsActive    bool      `json:"is_active"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ProductId      int       `json:"product_id" gorm:"primaryKey;autoIncrement:true"`
+	Sku            string    `json:"sku" gorm:"uniqueIndex"`
+	Price          float64   `json:"price"`
+	Name           string    `json:"name"`
+	Category       string    `json:"category"`
+	IsActive       bool      `json:"is_active"`
+	Description    string    `json:"description"`
+	IdempotencyKey *string   `json:"idempotency_key,omitempty" gorm:"uniqueIndex"` // NULL when created without a key
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	// DeletedAt soft-deletes the product; its SKU stays reserved so it can't be re-added by mistake
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// ProductPriceHistory records one price change made through UpdateProduct
+type ProductPriceHistory struct {
+	Id        int       `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	ProductId int       `json:"product_id" gorm:"index;not null"`
+	OldPrice  float64   `json:"old_price"`
+	NewPrice  float64   `json:"new_price"`
+	ChangedAt time.Time `json:"changed_at" gorm:"index"`
+}
+
+// UpdateProductRequest represents a partial product update; only provided fields are applied
+type UpdateProductRequest struct {
+	ProductId   int     `json:"product_id"`
+	Sku         string  `json:"sku"`
+	Price       float64 `json:"price"`
+	Name        string  `json:"name"`
+	Category    string  `json:"category"`
+	IsActive    *bool   `json:"is_active"`
+	Description string  `json:"description"`
+}
+
+// BulkDeleteRequest soft-deletes either a whole category or a list of SKUs, never both
+type BulkDeleteRequest struct {
+	Category string   `json:"category"`
+	Skus     []string `json:"skus" binding:"max=500"`
+}
+
+// ProductBundleItem is one component of a bundle product; a product with no rows here is standalone
+type ProductBundleItem struct {
+	Id          int `json:"-" gorm:"primaryKey;autoIncrement:true"`
+	BundleId    int `json:"bundle_id" gorm:"uniqueIndex:idx_bundle_component,priority:1;not null"`
+	ComponentId int `json:"product_id" gorm:"uniqueIndex:idx_bundle_component,priority:2;index;not null"`
+	Quantity    int `json:"quantity" gorm:"not null"`
+}
+
+// BundleComponent is one child product and how many units of it a bundle contains
+type BundleComponent struct {
+	ProductId int `json:"product_id" binding:"required"`
+	Quantity  int `json:"quantity" binding:"required,min=1"`
+}
+
+// SetBundleRequest replaces a product's bundle components; an empty list makes it standalone again
+type SetBundleRequest struct {
+	Components []BundleComponent `json:"components" binding:"max=50,dive"`
+}
+
+// ReserveBundleRequest reserves quantity bundles for an order by reserving each component
+type ReserveBundleRequest struct {
+	OrderId        string `json:"order_id" binding:"required"`
+	Quantity       int    `json:"quantity" binding:"required,min=1"`
+	IdempotencyKey string `json:"idempotency_key" binding:"required"`
+	Region         string `json:"region,omitempty"`
+}
+
+// CategoryModel places a product category under a parent category; categories without a row, or with
+// an empty ParentCategory, are top level
+type CategoryModel struct {
+	Name           string    `json:"category" gorm:"primaryKey"`
+	ParentCategory string    `json:"parent_category" gorm:"index"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// SetCategoryParentRequest moves a category under ParentCategory; an empty parent makes it top level
+type SetCategoryParentRequest struct {
+	ParentCategory string `json:"parent_category"`
 }
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret"
+
+// useSecret loads testSecret as the signing key for one test
+func useSecret(t *testing.T) {
+	t.Helper()
+	t.Setenv("JWT_SECRET", testSecret)
+	if err := LoadSecret(); err != nil {
+		t.Fatalf("load secret: %v", err)
+	}
+}
+
+// signClaims signs claims with key the way CustomerLogin does
+func signClaims(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return token
+}
+
+func TestAuthRequired(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useSecret(t)
+
+	router := gin.New()
+	router.GET("/protected", AuthRequired(), func(c *gin.Context) {
+		customerId, err := CustomerId(c)
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.String(http.StatusOK, strconv.Itoa(customerId)+" "+c.GetString(RoleKey))
+	})
+
+	valid := jwt.MapClaims{"sub": 42, "exp": time.Now().Add(time.Hour).Unix()}
+	tests := []struct {
+		name     string
+		header   string
+		status   int
+		wantBody string
+	}{
+		{"valid", "Bearer " + signClaims(t, jwt.SigningMethodHS256, []byte(testSecret), valid), http.StatusOK, "42 customer"},
+		{"valid admin", "Bearer " + signClaims(t, jwt.SigningMethodHS256, []byte(testSecret),
+			jwt.MapClaims{"sub": 7, "role": RoleAdmin, "exp": time.Now().Add(time.Hour).Unix()}), http.StatusOK, "7 admin"},
+		{"missing header", "", http.StatusUnauthorized, ""},
+		{"not a bearer token", "Basic dXNlcjpwYXNz", http.StatusUnauthorized, ""},
+		{"empty bearer token", "Bearer ", http.StatusUnauthorized, ""},
+		{"malformed", "Bearer not.a.jwt", http.StatusUnauthorized, ""},
+		{"expired", "Bearer " + signClaims(t, jwt.SigningMethodHS256, []byte(testSecret),
+			jwt.MapClaims{"sub": 42, "exp": time.Now().Add(-time.Minute).Unix()}), http.StatusUnauthorized, ""},
+		{"no expiry", "Bearer " + signClaims(t, jwt.SigningMethodHS256, []byte(testSecret), jwt.MapClaims{"sub": 42}), http.StatusUnauthorized, ""},
+		{"wrong key", "Bearer " + signClaims(t, jwt.SigningMethodHS256, []byte("other-secret"), valid), http.StatusUnauthorized, ""},
+		{"unsigned", "Bearer " + signClaims(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid), http.StatusUnauthorized, ""},
+		{"non-numeric subject", "Bearer " + signClaims(t, jwt.SigningMethodHS256, []byte(testSecret),
+			jwt.MapClaims{"sub": "someone", "exp": time.Now().Add(time.Hour).Unix()}), http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("got %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Fatalf("got body %q, want %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestAuthRequiredRejectsOldTokenVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useSecret(t)
+
+	// The customer changed their password once, so only version 1 tokens are current
+	TokenVersionLookup = func(customerId int) (int, error) { return 1, nil }
+	t.Cleanup(func() { TokenVersionLookup = nil })
+
+	router := gin.New()
+	router.GET("/protected", AuthRequired(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	exp := time.Now().Add(time.Hour).Unix()
+	for version, status := range map[int]int{0: http.StatusUnauthorized, 1: http.StatusOK} {
+		token := signClaims(t, jwt.SigningMethodHS256, []byte(testSecret), jwt.MapClaims{"sub": 42, "ver": version, "exp": exp})
+		req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != status {
+			t.Fatalf("token version %d: got %d, want %d", version, w.Code, status)
+		}
+	}
+}
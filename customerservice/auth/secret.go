@@ -0,0 +1,24 @@
+package auth
+
+import (
+	"errors"
+	"os"
+)
+
+// secret is the HS256 key shared by token signing and verification
+var secret string
+
+// LoadSecret reads JWT_SECRET from the environment and fails when it is unset
+func LoadSecret() error {
+	value := os.Getenv("JWT_SECRET")
+	if value == "" {
+		return errors.New("JWT_SECRET environment variable is not set")
+	}
+	secret = value
+	return nil
+}
+
+// Secret returns the loaded JWT signing key, or "" if LoadSecret has not succeeded
+func Secret() string {
+	return secret
+}
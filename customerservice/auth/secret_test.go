@@ -0,0 +1,22 @@
+package auth
+
+import "testing"
+
+func TestLoadSecret(t *testing.T) {
+	t.Setenv("JWT_SECRET", "")
+	secret = ""
+	if err := LoadSecret(); err == nil {
+		t.Fatal("LoadSecret succeeded without JWT_SECRET")
+	}
+	if Secret() != "" {
+		t.Fatalf("Secret() is %q after a failed load, want empty", Secret())
+	}
+
+	t.Setenv("JWT_SECRET", testSecret)
+	if err := LoadSecret(); err != nil {
+		t.Fatalf("LoadSecret: %v", err)
+	}
+	if Secret() != testSecret {
+		t.Fatalf("Secret() is %q, want %q", Secret(), testSecret)
+	}
+}
@@ -1,6 +1,8 @@
 package common
 
 import (
+	"time"
+
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
@@ -9,6 +11,13 @@ var Config *Configuration
 
 type Configuration struct {
 	Database DatabaseConfiguration
+	Admin    AdminConfiguration
+	Password PasswordPolicyConfiguration
+	Login    LoginThrottleConfiguration
+	Account  AccountConfiguration
+	Cors     CorsConfiguration
+	Reset    ResetConfiguration
+	Request  RequestConfiguration
 }
 
 type DatabaseConfiguration struct {
@@ -21,6 +30,70 @@ type DatabaseConfiguration struct {
 	MaxLifetime  int
 	MaxOpenConns int
 	MaxIdleConns int
+	// ConnectAttempts and ConnectBackoff control the startup retry; the backoff doubles after each failure
+	ConnectAttempts int
+	ConnectBackoff  time.Duration
+	// QueryTimeout bounds the request-scoped queries of the heavy read endpoints
+	QueryTimeout time.Duration
+}
+
+type AdminConfiguration struct {
+	Name     string
+	Email    string
+	Password string
+}
+
+// PasswordPolicyConfiguration sets the rules new passwords must meet
+type PasswordPolicyConfiguration struct {
+	MinLength     int
+	RequireLetter *bool
+	RequireDigit  *bool
+}
+
+// LoginThrottleConfiguration controls the failed-login lockout
+type LoginThrottleConfiguration struct {
+	MaxAttempts      int
+	MaxAttemptsPerIp int
+	Window           time.Duration
+	Lockout          time.Duration
+}
+
+// AccountConfiguration controls what happens when a customer deletes their account.
+// DeletionMode is "anonymize" (default) or "delete".
+type AccountConfiguration struct {
+	DeletionMode string
+}
+
+// ResetConfiguration guards POST /v1/admin/reset, which wipes every table; only enable it for e2e runs
+type ResetConfiguration struct {
+	Allowed bool
+}
+
+// RequestConfiguration caps request body sizes in bytes; MaxUploadBytes applies to multipart uploads
+type RequestConfiguration struct {
+	MaxBodyBytes   int64
+	MaxUploadBytes int64
+}
+
+// CorsConfiguration lists the browser origins allowed to call the API; empty denies all
+type CorsConfiguration struct {
+	AllowedOrigins []string
+}
+
+// databaseEnvBindings maps each database setting to the env vars that override it; the first one set wins
+var databaseEnvBindings = map[string][]string{
+	"database.driver":          {"DB_DRIVER"},
+	"database.host":            {"DB_HOST"},
+	"database.port":            {"DB_PORT"},
+	"database.dbname":          {"DB_NAME"},
+	"database.username":        {"DB_USER"},
+	"database.password":        {"DB_PASSWORD", "APP_DB_PASSWORD"},
+	"database.maxlifetime":     {"DB_MAX_LIFETIME"},
+	"database.maxopenconns":    {"DB_MAX_OPEN_CONNS"},
+	"database.maxidleconns":    {"DB_MAX_IDLE_CONNS"},
+	"database.connectattempts": {"DB_CONNECT_ATTEMPTS"},
+	"database.connectbackoff":  {"DB_CONNECT_BACKOFF"},
+	"database.querytimeout":    {"DB_QUERY_TIMEOUT"},
 }
 
 func ConfigSetup(configPath string) error {
@@ -34,6 +107,27 @@ func ConfigSetup(configPath string) error {
 		return err
 	}
 
+	// Database settings resolve as env var, then config file, then default
+	for key, envs := range databaseEnvBindings {
+		_ = viper.BindEnv(append([]string{key}, envs...)...)
+	}
+	viper.SetDefault("database.driver", "postgres")
+	viper.SetDefault("database.host", "postgres_main")
+	viper.SetDefault("database.port", "5432")
+
+	// Comma-separated origins, e.g. "https://admin.example.com,http://localhost:5173"
+	_ = viper.BindEnv("cors.allowedorigins", "CORS_ALLOWED_ORIGINS")
+
+	_ = viper.BindEnv("request.maxbodybytes", "MAX_BODY_BYTES")
+	_ = viper.BindEnv("request.maxuploadbytes", "MAX_UPLOAD_BYTES")
+
+	// Keep the bootstrap admin's credentials out of the config file
+	_ = viper.BindEnv("admin.email", "ADMIN_EMAIL")
+	_ = viper.BindEnv("admin.password", "ADMIN_PASSWORD")
+
+	// Table resets stay off unless an e2e environment opts in
+	_ = viper.BindEnv("reset.allowed", "ALLOW_RESET")
+
 	err := viper.Unmarshal(&configuration)
 	if err != nil {
 		log.Fatalf("Unable to decode into struct, %v", err)
@@ -0,0 +1,41 @@
+package common
+
+import "github.com/gin-gonic/gin"
+
+// ErrorResponse is the body of every error reply
+type ErrorResponse struct {
+	Code    string `json:"code" example:"NOT_FOUND"`
+	Message string `json:"message" example:"Resource not found"`
+	Details any    `json:"details,omitempty"`
+}
+
+// Error codes returned in ErrorResponse.Code
+const (
+	CodeInvalidRequest = "INVALID_REQUEST"
+	CodeUnauthorized   = "UNAUTHORIZED"
+	CodePaymentFailed  = "PAYMENT_FAILED"
+	CodeForbidden      = "FORBIDDEN"
+	CodeNotFound       = "NOT_FOUND"
+	CodeConflict       = "CONFLICT"
+	CodeRateLimited    = "RATE_LIMITED"
+	CodeInternal       = "INTERNAL_ERROR"
+	CodeUpstream       = "UPSTREAM_ERROR"
+	CodeTimeout        = "TIMEOUT"
+
+	CodePayloadTooLarge = "PAYLOAD_TOO_LARGE"
+)
+
+// RespondError writes an ErrorResponse with the given status, code and message
+func RespondError(c *gin.Context, status int, code string, message string) {
+	c.IndentedJSON(status, ErrorResponse{Code: code, Message: message})
+}
+
+// RespondErrorWithDetails writes an ErrorResponse carrying extra context for the client
+func RespondErrorWithDetails(c *gin.Context, status int, code string, message string, details any) {
+	c.IndentedJSON(status, ErrorResponse{Code: code, Message: message, Details: details})
+}
+
+// AbortWithError writes an ErrorResponse and stops the handler chain; used by middleware
+func AbortWithError(c *gin.Context, status int, code string, message string) {
+	c.AbortWithStatusJSON(status, ErrorResponse{Code: code, Message: message})
+}
@@ -0,0 +1,78 @@
+package common
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPaginate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantLimit  int
+		wantOffset int
+	}{
+		{"missing", "", DefaultPageSize, 0},
+		{"non-numeric", "?limit=ten&page=two", DefaultPageSize, 0},
+		{"zero limit and page", "?limit=0&page=0", DefaultPageSize, 0},
+		{"negative limit and page", "?limit=-5&page=-2", DefaultPageSize, 0},
+		{"negative offset falls back to page", "?limit=10&offset=-1&page=3", 10, 20},
+		{"limit over max", "?limit=1000", MaxPageSize, 0},
+		{"max limit", "?limit=100&page=2", MaxPageSize, MaxPageSize},
+		{"page", "?limit=10&page=3", 10, 20},
+		{"offset wins over page", "?limit=10&offset=5&page=3", 10, 5},
+		{"zero offset", "?offset=0&page=3", DefaultPageSize, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/items"+tt.query, nil)
+
+			limit, offset := Paginate(c)
+			if limit != tt.wantLimit || offset != tt.wantOffset {
+				t.Fatalf("Paginate(%q) = %d, %d, want %d, %d", tt.query, limit, offset, tt.wantLimit, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestNewPageResponse(t *testing.T) {
+	tests := []struct {
+		name           string
+		total          int64
+		limit, offset  int
+		wantPage       int
+		wantTotalPages int64
+	}{
+		{"empty", 0, 20, 0, 1, 0},
+		{"exact pages", 40, 20, 20, 2, 2},
+		{"partial last page", 41, 20, 40, 3, 3},
+		{"offset inside a page", 41, 20, 25, 2, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := NewPageResponse([]int{}, tt.total, tt.limit, tt.offset)
+			if page.Page != tt.wantPage || page.TotalPages != tt.wantTotalPages || page.Limit != tt.limit || page.Total != tt.total {
+				t.Fatalf("got %+v, want page %d of %d", page, tt.wantPage, tt.wantTotalPages)
+			}
+		})
+	}
+}
+
+func TestWithLegacyKey(t *testing.T) {
+	items := []string{"a", "b"}
+	body := NewPageResponse(items, 2, 20, 0).WithLegacyKey("things", len(items))
+
+	for _, key := range []string{"things", "items"} {
+		if got, ok := body[key].([]string); !ok || len(got) != 2 {
+			t.Fatalf("%s is %v, want the page's items", key, body[key])
+		}
+	}
+	if body["count"] != 2 || body["total"] != int64(2) || body["total_pages"] != int64(1) {
+		t.Fatalf("got metadata %v", body)
+	}
+}
@@ -3,7 +3,6 @@ package database
 import (
 	common "customerservice/common"
 	models "customerservice/models"
-	"os"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -16,6 +15,9 @@ var (
 	err  error
 )
 
+// defaultHost is used when neither the config file nor DB_HOST names a database host
+const defaultHost = "postgres_main"
+
 type Repository struct {
 	Database *gorm.DB
 }
@@ -32,27 +34,19 @@ func SetupDB(configuration *common.Configuration) error {
 	port := configuration.Database.Port
 
 	//host := os.Getenv("MY_POD_IP")
-	log.Infof("in line 35")
 	host := configuration.Database.Host
 	if host != "" {
-		log.Infof("line xxx")
-		host = "postgres_main"
 		log.Info("Host IP is ", host)
 	} else {
-		log.Error("Host is Empty in Env Variable")
-	}
-
-	pw := os.Getenv("APP_DB_PASSWORD")
-	if pw != "" {
-		password = pw
+		host = defaultHost
+		log.Warn("Host is Empty in config and DB_HOST, falling back to ", host)
 	}
 
 	// data source name
 	dsn := "host=" + host + " user=" + username + " password=" + password + " port=" + port + " dbname=" + dbname
 	if driver == "postgres" { // Postgres DB
-		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		db, err = openWithRetry(postgres.Open(dsn), &gorm.Config{}, configuration.Database)
 		if err != nil {
-			log.Error("db err: ", err)
 			return err
 		}
 	}
@@ -75,7 +69,7 @@ func SetupDB(configuration *common.Configuration) error {
 // Auto migrate project models
 func migrateModels() {
 	// Add equipment models so tables for categories and equipment are migrated
-	err = Repo.Database.AutoMigrate(&models.CustomerDetail{})
+	err = Repo.Database.AutoMigrate(&models.CustomerDetail{}, &models.PasswordResetToken{}, &models.RefreshToken{}, &models.EmailChangeToken{})
 	if err != nil {
 		log.Error("Auto-migrate error: ", err)
 	}
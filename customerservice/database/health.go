@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	common "customerservice/common"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pingTimeout bounds how long a health probe waits on the database
+const pingTimeout = 2 * time.Second
+
+// Ping checks that the database is reachable within the given timeout
+func Ping(timeout time.Duration) error {
+	if Repo.Database == nil {
+		return errors.New("database is not initialized")
+	}
+
+	sqlDB, dbErr := Repo.Database.DB()
+	if dbErr != nil {
+		return dbErr
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return sqlDB.PingContext(ctx)
+}
+
+// LivenessCheck reports that the process is serving requests; it never touches the database
+// so a database outage doesn't get the service restarted.
+func LivenessCheck(service string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "alive", "service": service})
+	}
+}
+
+// ReadinessCheck reports whether the service can handle traffic, which requires a reachable database
+func ReadinessCheck(service string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := Ping(pingTimeout); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unhealthy", "db": "down", "service": service})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "healthy", "db": "up", "service": service})
+	}
+}
+
+// DbStats reports the connection pool statistics, to tell whether requests are queueing for a connection
+func DbStats(service string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if Repo.Database == nil {
+			common.RespondError(c, http.StatusServiceUnavailable, common.CodeInternal, "database is not initialized")
+			return
+		}
+		sqlDB, err := Repo.Database.DB()
+		if err != nil {
+			common.RespondError(c, http.StatusServiceUnavailable, common.CodeInternal, err.Error())
+			return
+		}
+
+		stats := sqlDB.Stats()
+		c.JSON(http.StatusOK, gin.H{
+			"service":              service,
+			"max_open_connections": stats.MaxOpenConnections,
+			"open_connections":     stats.OpenConnections,
+			"in_use":               stats.InUse,
+			"idle":                 stats.Idle,
+			"wait_count":           stats.WaitCount,
+			"wait_duration_ms":     stats.WaitDuration.Milliseconds(),
+			"max_idle_closed":      stats.MaxIdleClosed,
+			"max_lifetime_closed":  stats.MaxLifetimeClosed,
+		})
+	}
+}
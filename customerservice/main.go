@@ -2,13 +2,16 @@ package main
 
 // Swagger docs
 import (
+	auth "customerservice/auth"
 	common "customerservice/common"
 	database "customerservice/database"
+	models "customerservice/models"
 
 	// docs "customerservice/docs" // generated by swag - temporarily commented for Docker build
 	userservice "customerservice/user"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	log "github.com/sirupsen/logrus"
 	// swaggerFiles "github.com/swaggo/files"
 	// ginSwagger "github.com/swaggo/gin-swagger"
@@ -24,6 +27,11 @@ import (
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
 func main() {
+	// Tokens must never be signed with a fallback key, so a missing secret is fatal
+	if err := auth.LoadSecret(); err != nil {
+		log.Fatalf("JWT secret setup failed: %v", err)
+	}
+
 	err := common.ConfigSetup("configuration/dbconfig.yaml")
 	if err != nil {
 		log.Error("ConfigSetup failed")
@@ -40,22 +48,26 @@ func main() {
 		log.Info("DB Setup Success")
 	}
 
-	router := gin.Default()
+	if err := userservice.EnsureAdmin(configuration.Admin); err != nil {
+		log.Errorf("Admin bootstrap failed: %v", err)
+	}
 
-	// Add CORS middleware
-	router.Use(func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH")
+	// JSON bodies with unknown fields are rejected, so a misspelled field is a 400 rather than silently ignored
+	binding.EnableDecoderDisallowUnknownFields = true
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
+	// RequestLogger replaces gin's default access log with one structured line per request
+	router := gin.New()
+	router.Use(gin.Recovery(), common.RequestLogger())
+	router.Use(common.CORS(configuration.Cors.AllowedOrigins))
+	router.Use(common.LimitRequestBody(configuration.Request.MaxBodyBytes, configuration.Request.MaxUploadBytes))
 
-		c.Next()
-	})
+	// Health checks: /live for liveness, /ready (and /health) for readiness, which pings the database
+	router.GET("/live", database.LivenessCheck("customer"))
+	router.GET("/ready", database.ReadinessCheck("customer"))
+	router.GET("/health", database.ReadinessCheck("customer"))
+
+	// Connection pool stats, for diagnosing pool exhaustion under load
+	router.GET("/debug/dbstats", database.DbStats("customer"))
 
 	// Swagger setup temporarily commented for Docker build
 	// docs.SwaggerInfo.Description = "API for customer service"
@@ -69,6 +81,34 @@ func main() {
 	// Public routes
 	router.POST("/api/customersignup", userservice.AddNewCustomer)
 	router.POST("/api/customerlogin", userservice.CustomerLogin)
+	router.POST("/api/customer/forgot-password", userservice.ForgotPassword)
+	router.POST("/api/customer/reset-password", userservice.ResetPassword)
+	router.POST("/api/customer/verify-email", userservice.VerifyEmail)
+	router.POST("/api/customer/refresh", userservice.RefreshAccessToken)
+	router.POST("/api/customer/logout", userservice.Logout)
+
+	// Versioned aliases so clients can use the same /v1 prefix as the other services
+	router.POST("/v1/customersignup", userservice.AddNewCustomer)
+	router.POST("/v1/customerlogin", userservice.CustomerLogin)
+
+	// Authenticated routes
+	auth.TokenVersionLookup = userservice.CurrentTokenVersion
+	protected := router.Group("/api", auth.AuthRequired())
+	{
+		protected.GET("/customer/profile", userservice.GetCustomerProfile)
+		protected.POST("/customer/change-password", userservice.ChangePassword)
+		protected.POST("/customer/change-email", userservice.ChangeEmail)
+		protected.DELETE("/customer/me", userservice.DeleteAccount)
+	}
+
+	// Wipes all customer data for e2e runs; refused unless ALLOW_RESET is set
+	router.POST("/v1/admin/reset", database.ResetTables("customer", &models.CustomerDetail{}, &models.PasswordResetToken{}, &models.RefreshToken{}, &models.EmailChangeToken{}))
+
+	// Admin routes
+	admin := router.Group("/v1", auth.AuthRequired(), auth.RequireRole(auth.RoleAdmin))
+	{
+		admin.GET("/customers", userservice.ListCustomers)
+	}
 
 	router.Run(":3000")
 }
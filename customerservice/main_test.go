@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestMainFailsWithoutJWTSecret runs main in a child process, since a missing secret exits the process
+func TestMainFailsWithoutJWTSecret(t *testing.T) {
+	if os.Getenv("CUSTOMERSERVICE_RUN_MAIN") == "1" {
+		main()
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestMainFailsWithoutJWTSecret$")
+	for _, variable := range os.Environ() {
+		if !strings.HasPrefix(variable, "JWT_SECRET=") {
+			cmd.Env = append(cmd.Env, variable)
+		}
+	}
+	cmd.Env = append(cmd.Env, "CUSTOMERSERVICE_RUN_MAIN=1")
+
+	output, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Success() {
+		t.Fatalf("startup without JWT_SECRET did not fail (err %v): %s", err, output)
+	}
+	if !strings.Contains(string(output), "JWT_SECRET") {
+		t.Fatalf("startup failure does not mention JWT_SECRET: %s", output)
+	}
+}
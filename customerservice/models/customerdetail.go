@@ -14,10 +14,75 @@ type CustomerDetail struct {
 	EmailAddress string     `json:"email_address" gorm:"unique;not null"`
 	PhoneNumber  string     `json:"phonenumber" gorm:"not null"`
 	Password     string     `json:"password" gorm:"not null"`
-	CreateAt     *time.Time `json:"created_at,omitempty" gorm:"column:created_at"`
+	TokenVersion int        `json:"-" gorm:"not null;default:0"`
+	Role         string     `json:"role" gorm:"not null;default:customer"`
+	CreateAt     *time.Time `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+	ErasedAt     *time.Time `json:"erased_at,omitempty"`
 }
 
 type UserLoginModel struct {
 	EmailAddress string `json:"email_address"`
 	Password     string `json:"password"`
 }
+
+type ChangePasswordModel struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+type DeleteAccountModel struct {
+	Password string `json:"password" binding:"required"`
+}
+
+type ForgotPasswordModel struct {
+	EmailAddress string `json:"email_address" binding:"required"`
+}
+
+type ResetPasswordModel struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// PasswordResetToken is a single-use password reset token; only its SHA-256 hash is stored
+type PasswordResetToken struct {
+	ID         int        `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	CustomerId int        `json:"customer_id" gorm:"index;not null"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	UsedAt     *time.Time `json:"used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+type ChangeEmailModel struct {
+	NewEmail string `json:"new_email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type VerifyEmailModel struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// EmailChangeToken holds a pending login email until the new address is verified; only the token's hash is stored
+type EmailChangeToken struct {
+	ID         int        `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	CustomerId int        `json:"customer_id" gorm:"index;not null"`
+	NewEmail   string     `json:"new_email" gorm:"not null"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	UsedAt     *time.Time `json:"used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+type RefreshTokenModel struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshToken is a server-side session that can mint new access tokens until revoked; only its hash is stored
+type RefreshToken struct {
+	ID         int        `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	CustomerId int        `json:"customer_id" gorm:"index;not null"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
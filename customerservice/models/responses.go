@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // Response represents a generic HTTP response
 type Response struct {
 	Message string `json:"message" example:"Success message or error details"`
@@ -7,5 +9,25 @@ type Response struct {
 
 // TokenResponse represents the response for successful login
 type TokenResponse struct {
-	AccessToken string `json:"access_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	AccessToken  string `json:"access_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	RefreshToken string `json:"refresh_token,omitempty" example:"9f86d081884c7d659a2feaa0c55ad015..."`
+	ExpiresIn    int    `json:"expires_in" example:"900"`
+}
+
+// CustomerProfile represents the authenticated customer's own details
+type CustomerProfile struct {
+	CustomerId   int    `json:"customer_id" example:"42"`
+	Name         string `json:"name" example:"Jane Doe"`
+	EmailAddress string `json:"email_address" example:"jane@example.com"`
+	PhoneNumber  string `json:"phonenumber" example:"+919876543210"`
+}
+
+// CustomerSummary is a customer as shown in the admin listing
+type CustomerSummary struct {
+	CustomerId   int        `json:"customer_id" example:"42"`
+	Name         string     `json:"name" example:"Jane Doe"`
+	EmailAddress string     `json:"email_address" example:"jane@example.com"`
+	PhoneNumber  string     `json:"phonenumber" example:"+919876543210"`
+	Role         string     `json:"role" example:"customer"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
 }
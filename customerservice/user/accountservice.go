@@ -0,0 +1,123 @@
+package user
+
+import (
+	auth "customerservice/auth"
+	common "customerservice/common"
+	database "customerservice/database"
+	models "customerservice/models"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/martian/log"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Account deletion modes
+const (
+	DeletionModeAnonymize = "anonymize"
+	DeletionModeDelete    = "delete"
+)
+
+// erasedEmailDomain keeps anonymized addresses unique without being deliverable
+const erasedEmailDomain = "erased.invalid"
+
+// @Summary Delete account
+// @Description Erase the authenticated customer's account after confirming the password and sign out every session
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param confirmation body models.DeleteAccountModel true "Current password"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} common.ErrorResponse
+// @Failure 401 {object} common.ErrorResponse
+// @Failure 404 {object} common.ErrorResponse
+// @Failure 500 {object} common.ErrorResponse
+// @Router /customer/me [delete]
+func DeleteAccount(c *gin.Context) {
+	customerId, err := auth.CustomerId(c)
+	if err != nil {
+		common.RespondError(c, http.StatusUnauthorized, common.CodeUnauthorized, "invalid token")
+		return
+	}
+
+	var req models.DeleteAccountModel
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("JSON binding error %v", err)
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	db := database.GetDB()
+
+	var customer models.CustomerDetail
+	if err := db.Where("customer_id = ?", customerId).First(&customer).Error; err != nil {
+		log.Errorf("DB query error %v", err)
+		common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "customer not found")
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(customer.Password), []byte(req.Password)); err != nil {
+		common.RespondError(c, http.StatusUnauthorized, common.CodeUnauthorized, "invalid credentials")
+		return
+	}
+
+	mode := accountDeletionMode()
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("customer_id = ?", customer.CustomerId).Delete(&models.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		// A pending email change would otherwise put PII back on an erased row
+		if err := tx.Where("customer_id = ?", customer.CustomerId).Delete(&models.EmailChangeToken{}).Error; err != nil {
+			return err
+		}
+
+		if mode == DeletionModeDelete {
+			if err := tx.Where("customer_id = ?", customer.CustomerId).Delete(&models.RefreshToken{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&customer).Error
+		}
+
+		if err := revokeRefreshTokens(tx, customer.CustomerId); err != nil {
+			return err
+		}
+		return anonymizeCustomer(tx, customer)
+	})
+	if err != nil {
+		log.Errorf("account deletion error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "could not delete account")
+		return
+	}
+
+	log.Infof("customer %d erased their account (%s)", customer.CustomerId, mode)
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "account deleted"})
+}
+
+// anonymizeCustomer strips the customer's PII but keeps the row, so historical orders still resolve its ID.
+// The empty password hash can never match and the bumped token version rejects outstanding access tokens.
+func anonymizeCustomer(tx *gorm.DB, customer models.CustomerDetail) error {
+	now := time.Now()
+	return tx.Model(&customer).Updates(map[string]interface{}{
+		"name":          "Deleted customer",
+		"email_address": fmt.Sprintf("deleted-%d@%s", customer.CustomerId, erasedEmailDomain),
+		"phone_number":  "",
+		"password":      "",
+		"token_version": customer.TokenVersion + 1,
+		"erased_at":     &now,
+	}).Error
+}
+
+// accountDeletionMode returns the configured deletion mode, defaulting to anonymize
+func accountDeletionMode() string {
+	if config := common.GetConfig(); config != nil {
+		if strings.EqualFold(config.Account.DeletionMode, DeletionModeDelete) {
+			return DeletionModeDelete
+		}
+	}
+	return DeletionModeAnonymize
+}
@@ -0,0 +1,66 @@
+package user
+
+import (
+	auth "customerservice/auth"
+	common "customerservice/common"
+	database "customerservice/database"
+	models "customerservice/models"
+	"errors"
+	"fmt"
+
+	"github.com/google/martian/log"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// EnsureAdmin creates the configured bootstrap admin, or promotes the account if it already exists and
+// its password is the configured one. An account someone else registered under the admin email is never
+// promoted. It does nothing when no admin email and password are configured.
+func EnsureAdmin(admin common.AdminConfiguration) error {
+	if admin.Email == "" || admin.Password == "" {
+		log.Infof("no bootstrap admin configured (set ADMIN_EMAIL and ADMIN_PASSWORD)")
+		return nil
+	}
+
+	db := database.GetDB()
+
+	var existing models.CustomerDetail
+	err := db.Where("email_address = ?", admin.Email).First(&existing).Error
+	if err == nil {
+		if existing.Role != auth.RoleAdmin {
+			if bcrypt.CompareHashAndPassword([]byte(existing.Password), []byte(admin.Password)) != nil {
+				return fmt.Errorf("refusing to promote %s: the existing account's password does not match ADMIN_PASSWORD", admin.Email)
+			}
+			log.Infof("promoting %s to admin", admin.Email)
+			return db.Model(&existing).Update("role", auth.RoleAdmin).Error
+		}
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(admin.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	name := admin.Name
+	if name == "" {
+		name = "Administrator"
+	}
+
+	adminUser := models.CustomerDetail{
+		Name:         name,
+		EmailAddress: admin.Email,
+		PhoneNumber:  "",
+		Password:     string(hashedPassword),
+		Role:         auth.RoleAdmin,
+	}
+	if err := db.Create(&adminUser).Error; err != nil {
+		return err
+	}
+
+	log.Infof("created bootstrap admin %s", admin.Email)
+	return nil
+}
@@ -0,0 +1,49 @@
+package user
+
+import (
+	auth "customerservice/auth"
+	common "customerservice/common"
+	dbtest "customerservice/database/dbtest"
+	models "customerservice/models"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestEnsureAdminExistingAccount(t *testing.T) {
+	admin := common.AdminConfiguration{Email: "admin@example.com", Password: "0perator-Secret"}
+
+	tests := []struct {
+		name     string
+		password string // the password the account was registered with
+		wantErr  bool
+		wantRole string
+	}{
+		{"pre-registered by someone else", "Squatter123", true, auth.RoleCustomer},
+		{"registered by the operator", admin.Password, false, auth.RoleAdmin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.Open(t, &models.CustomerDetail{})
+			hash, err := bcrypt.GenerateFromPassword([]byte(tt.password), bcrypt.MinCost)
+			if err != nil {
+				t.Fatalf("hash password: %v", err)
+			}
+			if err := db.Create(&models.CustomerDetail{Name: "Someone", EmailAddress: admin.Email, Password: string(hash), Role: auth.RoleCustomer}).Error; err != nil {
+				t.Fatalf("create customer: %v", err)
+			}
+
+			if err := EnsureAdmin(admin); (err != nil) != tt.wantErr {
+				t.Fatalf("EnsureAdmin error %v, want error %v", err, tt.wantErr)
+			}
+
+			var stored models.CustomerDetail
+			if err := db.First(&stored, "email_address = ?", admin.Email).Error; err != nil {
+				t.Fatalf("load customer: %v", err)
+			}
+			if stored.Role != tt.wantRole {
+				t.Fatalf("role is %s, want %s", stored.Role, tt.wantRole)
+			}
+		})
+	}
+}
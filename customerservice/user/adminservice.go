@@ -0,0 +1,82 @@
+package user
+
+import (
+	common "customerservice/common"
+	database "customerservice/database"
+	models "customerservice/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/martian/log"
+	"gorm.io/gorm"
+)
+
+// likeEscaper escapes LIKE wildcards so search terms match literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// @Summary List customers
+// @Description Admin-only paginated customer listing with optional email and name substring filters. The page is also returned under `customers`, with its `count`, for older clients.
+// @Tags admin
+// @Produce json
+// @Security Bearer
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param email query string false "Email substring"
+// @Param name query string false "Name substring"
+// @Success 200 {object} common.PageResponse{items=[]models.CustomerSummary}
+// @Failure 400 {object} common.ErrorResponse
+// @Failure 401 {object} common.ErrorResponse
+// @Failure 403 {object} common.ErrorResponse
+// @Failure 500 {object} common.ErrorResponse
+// @Failure 503 {object} common.ErrorResponse
+// @Router /v1/customers [get]
+func ListCustomers(c *gin.Context) {
+	limit, offset := common.Paginate(c)
+
+	db, cancel := database.RequestDB(c)
+	defer cancel()
+
+	query := db.Model(&models.CustomerDetail{})
+	if email := strings.TrimSpace(c.Query("email")); email != "" {
+		query = query.Where(`LOWER(email_address) LIKE ? ESCAPE '\'`, "%"+likeEscaper.Replace(strings.ToLower(email))+"%")
+	}
+	if name := strings.TrimSpace(c.Query("name")); name != "" {
+		query = query.Where(`LOWER(name) LIKE ? ESCAPE '\'`, "%"+likeEscaper.Replace(strings.ToLower(name))+"%")
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		if database.RespondIfTimedOut(c, err) {
+			return
+		}
+		log.Errorf("DB count error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "could not list customers")
+		return
+	}
+
+	var customers []models.CustomerDetail
+	if err := query.Order("customer_id asc").Limit(limit).Offset(offset).Find(&customers).Error; err != nil {
+		if database.RespondIfTimedOut(c, err) {
+			return
+		}
+		log.Errorf("DB query error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "could not list customers")
+		return
+	}
+
+	// Password hashes and token versions never leave the service
+	summaries := make([]models.CustomerSummary, 0, len(customers))
+	for _, customer := range customers {
+		summaries = append(summaries, models.CustomerSummary{
+			CustomerId:   customer.CustomerId,
+			Name:         customer.Name,
+			EmailAddress: customer.EmailAddress,
+			PhoneNumber:  customer.PhoneNumber,
+			Role:         customer.Role,
+			CreatedAt:    customer.CreateAt,
+		})
+	}
+
+	c.IndentedJSON(http.StatusOK, common.NewPageResponse(summaries, total, limit, offset).WithLegacyKey("customers", len(summaries)))
+}
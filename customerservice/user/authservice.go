@@ -1,10 +1,16 @@
 package user
 
 import (
+	auth "customerservice/auth"
+	common "customerservice/common"
 	database "customerservice/database"
 	models "customerservice/models"
+	"errors"
+	"math"
 	"net/http"
-	"os"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -20,20 +26,34 @@ import (
 // @Produce json
 // @Param user body models.CustomerDetail true "Customer registration details"
 // @Success 200 {object} models.Response
-// @Failure 400 {object} models.Response
-// @Failure 409 {object} models.Response
-// @Failure 500 {object} models.Response
+// @Failure 400 {object} common.ErrorResponse
+// @Failure 409 {object} common.ErrorResponse
+// @Failure 500 {object} common.ErrorResponse
 // @Router /customersignup [post]
 func AddNewCustomer(c *gin.Context) {
 	var userSignUpModel models.CustomerDetail
 	if err := c.ShouldBind(&userSignUpModel); err != nil {
 		log.Errorf("FORM binding error %v", err.Error())
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, err.Error())
 		return
 	}
 
 	if userSignUpModel.Name == "" || userSignUpModel.EmailAddress == "" || userSignUpModel.Password == "" {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Some of the fields are not having right values"})
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Some of the fields are not having right values")
+		return
+	}
+
+	if invalid := invalidSignupFields(&userSignUpModel); len(invalid) > 0 {
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Some of the fields are not having right values", gin.H{
+			"invalid_fields": invalid,
+		})
+		return
+	}
+
+	if unmet := passwordPolicyViolations(userSignUpModel.Password); len(unmet) > 0 {
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "password does not meet the requirements", gin.H{
+			"requirements": unmet,
+		})
 		return
 	}
 
@@ -41,11 +61,13 @@ func AddNewCustomer(c *gin.Context) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(userSignUpModel.Password), bcrypt.DefaultCost)
 	if err != nil {
 		log.Errorf("password hash error %v", err.Error())
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Error processing password"})
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Error processing password")
 		return
 	}
 	userSignUpModel.Password = string(hashedPassword)
-	userSignUpModel.CreateAt = func(t time.Time) *time.Time { return &t }(time.Now())
+	userSignUpModel.CreateAt = nil           // stamped by GORM on insert; never taken from the client
+	userSignUpModel.ErasedAt = nil           // only set by account erasure
+	userSignUpModel.Role = auth.RoleCustomer // roles are never self-assigned at signup
 
 	db := database.GetDB()
 
@@ -54,19 +76,19 @@ func AddNewCustomer(c *gin.Context) {
 		Where("email_address = ?", userSignUpModel.EmailAddress).
 		Count(&count).Error; err != nil {
 		log.Errorf("DB count error %v", err.Error())
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Database error"})
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Database error")
 		return
 	}
 
 	if count > 0 {
-		c.IndentedJSON(http.StatusConflict, gin.H{"message": "Email address already exists"})
+		common.RespondError(c, http.StatusConflict, common.CodeConflict, "Email address already exists")
 		return
 	}
 
 	tx := db.Create(&userSignUpModel)
 	if tx.Error != nil {
 		log.Errorf("DB create error %v", tx.Error)
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Error saving data"})
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Error saving data")
 		return
 	}
 
@@ -74,6 +96,31 @@ func AddNewCustomer(c *gin.Context) {
 	c.IndentedJSON(http.StatusOK, "user created successfully.")
 }
 
+var (
+	// emailPattern is a pragmatic check for local@domain.tld
+	emailPattern = regexp.MustCompile(`^[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}$`)
+	// phonePattern accepts E.164-style numbers: optional +, then 8 to 15 digits not starting with 0
+	phonePattern = regexp.MustCompile(`^\+?[1-9][0-9]{7,14}$`)
+)
+
+// invalidSignupFields normalizes the email and phone and returns the names of fields with a bad format
+func invalidSignupFields(customer *models.CustomerDetail) []string {
+	invalid := []string{}
+
+	customer.EmailAddress = strings.TrimSpace(customer.EmailAddress)
+	if !emailPattern.MatchString(customer.EmailAddress) {
+		invalid = append(invalid, "email_address")
+	}
+
+	// Spaces, dashes and brackets are common in typed numbers and are dropped before checking
+	customer.PhoneNumber = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(customer.PhoneNumber)
+	if customer.PhoneNumber != "" && !phonePattern.MatchString(customer.PhoneNumber) {
+		invalid = append(invalid, "phonenumber")
+	}
+
+	return invalid
+}
+
 // @Summary Customer login
 // @Description Authenticate a user and return a JWT token
 // @Tags user
@@ -81,53 +128,80 @@ func AddNewCustomer(c *gin.Context) {
 // @Produce json
 // @Param credentials body models.UserLoginModel true "Login credentials"
 // @Success 200 {object} models.TokenResponse
-// @Failure 400 {object} models.Response
-// @Failure 401 {object} models.Response
-// @Failure 500 {object} models.Response
+// @Failure 400 {object} common.ErrorResponse
+// @Failure 401 {object} common.ErrorResponse
+// @Failure 429 {object} common.ErrorResponse
+// @Failure 500 {object} common.ErrorResponse
 // @Router /customerlogin [post]
 func CustomerLogin(c *gin.Context) {
 	var userLoginModel models.UserLoginModel
 	if err := c.ShouldBind(&userLoginModel); err != nil {
 		log.Errorf("FORM binding error %v", err)
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	throttle := currentLoginThrottle()
+	emailKey, ipKey := emailAttemptKey(userLoginModel.EmailAddress), ipAttemptKey(c.ClientIP())
+
+	// The lockout applies to unknown emails too, so it does not reveal whether an account exists
+	now := time.Now()
+	if wait := max(failedLogins.lockedFor(emailKey, now), failedLogins.lockedFor(ipKey, now)); wait > 0 {
+		retryAfter := int(math.Ceil(wait.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		common.RespondErrorWithDetails(c, http.StatusTooManyRequests, common.CodeRateLimited, "too many failed login attempts, try again later", gin.H{
+			"retry_after": retryAfter,
+		})
 		return
 	}
 
+	loginFailed := func() {
+		failedLogins.recordFailure(emailKey, throttle.maxAttempts, throttle.window, throttle.lockout, now)
+		failedLogins.recordFailure(ipKey, throttle.maxAttemptsPerIp, throttle.window, throttle.lockout, now)
+		// Do not reveal whether username or password was wrong
+		common.RespondError(c, http.StatusUnauthorized, common.CodeUnauthorized, "invalid credentials")
+	}
+
 	var existingUser models.CustomerDetail
 	db := database.GetDB()
 
 	// Lookup user by username only (password is hashed in DB)
 	if err := db.Where("email_address = ?", userLoginModel.EmailAddress).First(&existingUser).Error; err != nil {
 		log.Errorf("DB query error %v", err)
-		// Do not reveal whether username or password was wrong
-		c.IndentedJSON(http.StatusUnauthorized, gin.H{"message": "invalid credentials"})
+		// Burn the same bcrypt time as a wrong password so response timing doesn't reveal unknown emails
+		compareDummyPassword(userLoginModel.Password)
+		loginFailed()
+		return
+	}
+
+	// Erased accounts have no hash; compare against the dummy so they time like any other failure
+	if existingUser.Password == "" {
+		compareDummyPassword(userLoginModel.Password)
+		loginFailed()
 		return
 	}
 
 	// Compare provided password with hashed password stored in DB
 	if err := bcrypt.CompareHashAndPassword([]byte(existingUser.Password), []byte(userLoginModel.Password)); err != nil {
 		log.Errorf("password mismatch %v", err)
-		c.IndentedJSON(http.StatusUnauthorized, gin.H{"message": "invalid credentials"})
+		loginFailed()
 		return
 	}
 
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "JWT_SECRET" // replace
-	}
+	// Only the account's counter is cleared; the IP counter keeps running so one valid login can't mask guessing
+	failedLogins.reset(emailKey)
 
-	claims := jwt.MapClaims{
-		"sub":           existingUser.CustomerId,
-		"email_address": existingUser.EmailAddress,
-		"iat":           time.Now().Unix(),
-		"exp":           time.Now().Add(72 * time.Hour).Unix(),
+	tokenString, err := issueAccessToken(existingUser)
+	if err != nil {
+		log.Errorf("token sign error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "could not create token")
+		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(secret))
+	refreshToken, err := issueRefreshToken(database.GetDB(), existingUser.CustomerId)
 	if err != nil {
-		log.Errorf("token sign error %v", err)
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "could not create token"})
+		log.Errorf("refresh token error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "could not create token")
 		return
 	}
 
@@ -135,6 +209,38 @@ func CustomerLogin(c *gin.Context) {
 	existingUser.Password = ""
 
 	c.IndentedJSON(http.StatusOK, gin.H{
-		"access_token": tokenString,
+		"access_token":  tokenString,
+		"refresh_token": refreshToken,
+		"expires_in":    int(accessTokenTTL.Seconds()),
 	})
 }
+
+// issueAccessToken signs a short-lived JWT for the customer
+func issueAccessToken(customer models.CustomerDetail) (string, error) {
+	// Never sign with a guessable key; the service refuses to start without JWT_SECRET
+	secret := auth.Secret()
+	if secret == "" {
+		return "", errors.New("JWT secret is not configured")
+	}
+
+	claims := jwt.MapClaims{
+		"sub":           customer.CustomerId,
+		"email_address": customer.EmailAddress,
+		"ver":           customer.TokenVersion,
+		"role":          customer.Role,
+		"iat":           time.Now().Unix(),
+		"exp":           time.Now().Add(accessTokenTTL).Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret))
+}
+
+// dummyHash is generated at startup with the same cost as real password hashes
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password-for-timing"), bcrypt.DefaultCost)
+
+// compareDummyPassword runs a bcrypt compare against dummyHash so login failures take
+// similar time whether or not the account exists
+func compareDummyPassword(password string) {
+	_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
@@ -0,0 +1,104 @@
+package user
+
+import (
+	auth "customerservice/auth"
+	dbtest "customerservice/database/dbtest"
+	models "customerservice/models"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestInvalidSignupFields(t *testing.T) {
+	tests := []struct {
+		name        string
+		email       string
+		phone       string
+		wantInvalid []string
+		wantEmail   string
+		wantPhone   string
+	}{
+		{"valid", "jane.doe+shop@example.co.uk", "+14155552671", []string{}, "jane.doe+shop@example.co.uk", "+14155552671"},
+		{"email is trimmed", "  jane@example.com ", "", []string{}, "jane@example.com", ""},
+		{"phone is optional", "jane@example.com", "", []string{}, "jane@example.com", ""},
+		{"phone punctuation is dropped", "jane@example.com", "+91 (98765) 43-210", []string{}, "jane@example.com", "+919876543210"},
+		{"email without at", "notanemail", "", []string{"email_address"}, "notanemail", ""},
+		{"email without domain", "jane@", "", []string{"email_address"}, "jane@", ""},
+		{"email without tld", "jane@example", "", []string{"email_address"}, "jane@example", ""},
+		{"email with space", "jane doe@example.com", "", []string{"email_address"}, "jane doe@example.com", ""},
+		{"phone with letters", "jane@example.com", "555-CALL-NOW", []string{"phonenumber"}, "jane@example.com", "555CALLNOW"},
+		{"phone too short", "jane@example.com", "12345", []string{"phonenumber"}, "jane@example.com", "12345"},
+		{"phone too long", "jane@example.com", "+1234567890123456", []string{"phonenumber"}, "jane@example.com", "+1234567890123456"},
+		{"phone starting with zero", "jane@example.com", "0123456789", []string{"phonenumber"}, "jane@example.com", "0123456789"},
+		{"both invalid", "junk", "junk", []string{"email_address", "phonenumber"}, "junk", "junk"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customer := models.CustomerDetail{EmailAddress: tt.email, PhoneNumber: tt.phone}
+			invalid := invalidSignupFields(&customer)
+			if !reflect.DeepEqual(invalid, tt.wantInvalid) {
+				t.Fatalf("invalid fields %v, want %v", invalid, tt.wantInvalid)
+			}
+			if customer.EmailAddress != tt.wantEmail || customer.PhoneNumber != tt.wantPhone {
+				t.Fatalf("normalized to %q and %q, want %q and %q", customer.EmailAddress, customer.PhoneNumber, tt.wantEmail, tt.wantPhone)
+			}
+		})
+	}
+}
+
+func TestAddNewCustomerRejectsBadFormats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/v1/customersignup", AddNewCustomer)
+
+	body := `{"name":"Jane","email_address":"notanemail","phonenumber":"junk","password":"Str0ngPassword"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/customersignup", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body.String())
+	}
+	var response struct {
+		Details struct {
+			InvalidFields []string `json:"invalid_fields"`
+		} `json:"details"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if want := []string{"email_address", "phonenumber"}; !reflect.DeepEqual(response.Details.InvalidFields, want) {
+		t.Fatalf("invalid_fields %v, want %v: %s", response.Details.InvalidFields, want, w.Body.String())
+	}
+}
+
+func TestAddNewCustomerIgnoresServerFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useConfig(t, nil)
+	db := dbtest.Open(t, &models.CustomerDetail{})
+	router := gin.New()
+	router.POST("/v1/customersignup", AddNewCustomer)
+
+	body := `{"name":"Jane","email_address":"jane@example.com","password":"Str0ngPassword","role":"admin","erased_at":"2020-01-01T00:00:00Z"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/customersignup", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var stored models.CustomerDetail
+	if err := db.First(&stored, "email_address = ?", "jane@example.com").Error; err != nil {
+		t.Fatalf("load customer: %v", err)
+	}
+	if stored.ErasedAt != nil || stored.Role != auth.RoleCustomer {
+		t.Fatalf("got erased_at %v and role %s, want no erased_at and role %s", stored.ErasedAt, stored.Role, auth.RoleCustomer)
+	}
+}
@@ -0,0 +1,193 @@
+package user
+
+import (
+	auth "customerservice/auth"
+	common "customerservice/common"
+	database "customerservice/database"
+	models "customerservice/models"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/martian/log"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// emailChangeTokenTTL is how long a new email address has to be verified
+const emailChangeTokenTTL = 24 * time.Hour
+
+var (
+	// errInvalidEmailToken is returned when an email change token is unknown, used or expired
+	errInvalidEmailToken = errors.New("invalid or expired verification token")
+	// errEmailTaken is returned when another account claimed the new email before it was verified
+	errEmailTaken = errors.New("email address already exists")
+)
+
+// @Summary Request an email change
+// @Description Hold a new login email as pending and issue a token to verify it; login keeps using the current email until then
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body models.ChangeEmailModel true "New email and current password"
+// @Success 202 {object} models.Response
+// @Failure 400 {object} common.ErrorResponse
+// @Failure 401 {object} common.ErrorResponse
+// @Failure 500 {object} common.ErrorResponse
+// @Router /customer/change-email [post]
+func ChangeEmail(c *gin.Context) {
+	customerId, err := auth.CustomerId(c)
+	if err != nil {
+		common.RespondError(c, http.StatusUnauthorized, common.CodeUnauthorized, "invalid token")
+		return
+	}
+
+	var req models.ChangeEmailModel
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("JSON binding error %v", err)
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	newEmail := strings.TrimSpace(req.NewEmail)
+	if !emailPattern.MatchString(newEmail) {
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Some of the fields are not having right values", gin.H{
+			"invalid_fields": []string{"new_email"},
+		})
+		return
+	}
+
+	db := database.GetDB()
+
+	var customer models.CustomerDetail
+	if err := db.Where("customer_id = ?", customerId).First(&customer).Error; err != nil {
+		log.Errorf("DB query error %v", err)
+		common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "customer not found")
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(customer.Password), []byte(req.Password)); err != nil {
+		common.RespondError(c, http.StatusUnauthorized, common.CodeUnauthorized, "invalid credentials")
+		return
+	}
+
+	if newEmail == customer.EmailAddress {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "new email is the same as the current one")
+		return
+	}
+
+	// The response is identical whether or not the new email already has an account
+	response := gin.H{"message": "if the email address can be used, a verification link has been sent to it"}
+
+	var count int64
+	if err := db.Model(&models.CustomerDetail{}).Where("email_address = ?", newEmail).Count(&count).Error; err != nil {
+		log.Errorf("DB count error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Database error")
+		return
+	}
+	if count > 0 {
+		log.Infof("email change for customer %d skipped: target address already has an account", customer.CustomerId)
+		c.IndentedJSON(http.StatusAccepted, response)
+		return
+	}
+
+	token, err := newOpaqueToken()
+	if err != nil {
+		log.Errorf("email token generation error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Error processing request")
+		return
+	}
+
+	changeToken := models.EmailChangeToken{
+		CustomerId: customer.CustomerId,
+		NewEmail:   newEmail,
+		TokenHash:  hashToken(token),
+		ExpiresAt:  time.Now().Add(emailChangeTokenTTL),
+	}
+
+	// Only the latest request can be verified
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.EmailChangeToken{}).
+			Where("customer_id = ? AND used_at IS NULL", customer.CustomerId).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&changeToken).Error
+	})
+	if err != nil {
+		log.Errorf("DB create error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Error saving request")
+		return
+	}
+
+	// There is no mailer yet, so the token is only logged for operators
+	log.Infof("email change token for customer %d: %s (expires %s)", customer.CustomerId, token, changeToken.ExpiresAt.Format(time.RFC3339))
+
+	c.IndentedJSON(http.StatusAccepted, response)
+}
+
+// @Summary Verify a new email
+// @Description Make a pending email the customer's login email using the token sent to it
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param request body models.VerifyEmailModel true "Verification token"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} common.ErrorResponse
+// @Failure 409 {object} common.ErrorResponse
+// @Failure 500 {object} common.ErrorResponse
+// @Router /customer/verify-email [post]
+func VerifyEmail(c *gin.Context) {
+	var req models.VerifyEmailModel
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("JSON binding error %v", err)
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var changeToken models.EmailChangeToken
+		if err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashToken(req.Token), time.Now()).
+			First(&changeToken).Error; err != nil {
+			return errInvalidEmailToken
+		}
+
+		if err := tx.Model(&changeToken).Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		// Another account may have signed up with the address since the request was made
+		var count int64
+		if err := tx.Model(&models.CustomerDetail{}).
+			Where("email_address = ? AND customer_id <> ?", changeToken.NewEmail, changeToken.CustomerId).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errEmailTaken
+		}
+
+		return tx.Model(&models.CustomerDetail{}).
+			Where("customer_id = ?", changeToken.CustomerId).
+			Update("email_address", changeToken.NewEmail).Error
+	})
+
+	if errors.Is(err, errInvalidEmailToken) {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "invalid or expired verification token")
+		return
+	}
+	if errors.Is(err, errEmailTaken) {
+		common.RespondError(c, http.StatusConflict, common.CodeConflict, "Email address already exists")
+		return
+	}
+	if err != nil {
+		log.Errorf("email verification error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Error saving email address")
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "email address updated, use it to log in from now on"})
+}
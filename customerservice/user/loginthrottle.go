@@ -0,0 +1,128 @@
+package user
+
+import (
+	common "customerservice/common"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLoginMaxAttempts      = 5
+	defaultLoginMaxAttemptsPerIp = 20
+	defaultLoginWindow           = 15 * time.Minute
+	defaultLoginLockout          = 15 * time.Minute
+	loginAttemptSweepInterval    = 1 * time.Minute
+)
+
+// loginAttempts tracks failed logins for one email or client IP
+type loginAttempts struct {
+	failures    int
+	windowStart time.Time
+	lockedUntil time.Time
+}
+
+// loginAttemptStore is an in-memory failure counter; entries are evicted once their window and lockout have passed
+type loginAttemptStore struct {
+	mu        sync.Mutex
+	entries   map[string]*loginAttempts
+	lastSweep time.Time
+}
+
+var failedLogins = &loginAttemptStore{entries: map[string]*loginAttempts{}}
+
+// loginThrottle is the effective lockout configuration
+type loginThrottle struct {
+	maxAttempts      int
+	maxAttemptsPerIp int
+	window           time.Duration
+	lockout          time.Duration
+}
+
+// currentLoginThrottle reads the configured thresholds, falling back to 5 failures per email in 15 minutes
+func currentLoginThrottle() loginThrottle {
+	throttle := loginThrottle{
+		maxAttempts:      defaultLoginMaxAttempts,
+		maxAttemptsPerIp: defaultLoginMaxAttemptsPerIp,
+		window:           defaultLoginWindow,
+		lockout:          defaultLoginLockout,
+	}
+
+	if config := common.GetConfig(); config != nil {
+		if config.Login.MaxAttempts > 0 {
+			throttle.maxAttempts = config.Login.MaxAttempts
+		}
+		if config.Login.MaxAttemptsPerIp > 0 {
+			throttle.maxAttemptsPerIp = config.Login.MaxAttemptsPerIp
+		}
+		if config.Login.Window > 0 {
+			throttle.window = config.Login.Window
+		}
+		if config.Login.Lockout > 0 {
+			throttle.lockout = config.Login.Lockout
+		}
+	}
+	return throttle
+}
+
+func emailAttemptKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipAttemptKey(ip string) string {
+	return "ip:" + ip
+}
+
+// lockedFor returns how long the key stays locked out, or 0 if it is not locked
+func (s *loginAttemptStore) lockedFor(key string, now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.entries[key]; ok && now.Before(entry.lockedUntil) {
+		return entry.lockedUntil.Sub(now)
+	}
+	return 0
+}
+
+// recordFailure counts a failed login and locks the key once it reaches maxAttempts within the window
+func (s *loginAttemptStore) recordFailure(key string, maxAttempts int, window, lockout time.Duration, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(window, now)
+
+	entry, ok := s.entries[key]
+	if !ok || now.Sub(entry.windowStart) > window {
+		entry = &loginAttempts{windowStart: now}
+		s.entries[key] = entry
+	}
+
+	entry.failures++
+	if entry.failures >= maxAttempts {
+		entry.lockedUntil = now.Add(lockout)
+		entry.failures = 0
+		entry.windowStart = now
+	}
+}
+
+// reset clears the failure count for a key
+func (s *loginAttemptStore) reset(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+}
+
+// sweep drops entries whose window and lockout have both passed; callers must hold the lock
+func (s *loginAttemptStore) sweep(window time.Duration, now time.Time) {
+	if now.Sub(s.lastSweep) < loginAttemptSweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, entry := range s.entries {
+		if now.Sub(entry.windowStart) > window && now.After(entry.lockedUntil) {
+			delete(s.entries, key)
+		}
+	}
+}
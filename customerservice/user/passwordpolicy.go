@@ -0,0 +1,74 @@
+package user
+
+import (
+	common "customerservice/common"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const defaultPasswordMinLength = 8
+
+// passwordMaxBytes is bcrypt's input limit; GenerateFromPassword rejects anything longer
+const passwordMaxBytes = 72
+
+// passwordPolicy is the effective set of password rules
+type passwordPolicy struct {
+	minLength     int
+	requireLetter bool
+	requireDigit  bool
+}
+
+// currentPasswordPolicy reads the configured policy, falling back to 8 characters with letters and digits
+func currentPasswordPolicy() passwordPolicy {
+	policy := passwordPolicy{minLength: defaultPasswordMinLength, requireLetter: true, requireDigit: true}
+
+	if config := common.GetConfig(); config != nil {
+		if config.Password.MinLength > 0 {
+			policy.minLength = config.Password.MinLength
+		}
+		if config.Password.RequireLetter != nil {
+			policy.requireLetter = *config.Password.RequireLetter
+		}
+		if config.Password.RequireDigit != nil {
+			policy.requireDigit = *config.Password.RequireDigit
+		}
+	}
+	return policy
+}
+
+// passwordPolicyViolations lists every requirement the password fails; it is empty for an acceptable password.
+// Signup, change-password and reset-password all go through here so the rules stay in one place.
+func passwordPolicyViolations(password string) []string {
+	policy := currentPasswordPolicy()
+	unmet := []string{}
+
+	if len([]rune(password)) < policy.minLength {
+		unmet = append(unmet, fmt.Sprintf("password must be at least %d characters long", policy.minLength))
+	}
+	if len(password) > passwordMaxBytes {
+		unmet = append(unmet, fmt.Sprintf("password must be at most %d bytes long", passwordMaxBytes))
+	}
+
+	hasLetter, hasDigit := false, false
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	if policy.requireLetter && !hasLetter {
+		unmet = append(unmet, "password must contain at least one letter")
+	}
+	if policy.requireDigit && !hasDigit {
+		unmet = append(unmet, "password must contain at least one digit")
+	}
+	return unmet
+}
+
+// passwordStrengthError explains why a password is too weak, or returns "" if it is acceptable
+func passwordStrengthError(password string) string {
+	return strings.Join(passwordPolicyViolations(password), "; ")
+}
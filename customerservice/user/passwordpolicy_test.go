@@ -0,0 +1,106 @@
+package user
+
+import (
+	common "customerservice/common"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// useConfig swaps the package configuration for the duration of one test
+func useConfig(t *testing.T, config *common.Configuration) {
+	t.Helper()
+	previous := common.Config
+	common.Config = config
+	t.Cleanup(func() { common.Config = previous })
+}
+
+const (
+	tooShort  = "password must be at least 8 characters long"
+	noLetter  = "password must contain at least one letter"
+	noDigit   = "password must contain at least one digit"
+	tooShort4 = "password must be at least 4 characters long"
+	tooLong   = "password must be at most 72 bytes long"
+)
+
+func TestPasswordPolicyViolations(t *testing.T) {
+	useConfig(t, nil)
+
+	tests := []struct {
+		name     string
+		password string
+		want     []string
+	}{
+		{"single letter", "a", []string{tooShort, noDigit}},
+		{"empty", "", []string{tooShort, noLetter, noDigit}},
+		{"letters only", "password", []string{noDigit}},
+		{"digits only", "12345678", []string{noLetter}},
+		{"short with both", "abc123", []string{tooShort}},
+		{"symbols only", "!@#$%^&*", []string{noLetter, noDigit}},
+		{"strong", "passw0rd", []string{}},
+		{"strong with symbols", "C0rrect-Horse-Battery", []string{}},
+		{"length counts characters not bytes", "pässwö1d", []string{}},
+		{"at bcrypt's limit", strings.Repeat("a", 71) + "1", []string{}},
+		{"over bcrypt's limit", strings.Repeat("a", 72) + "1", []string{tooLong}},
+		{"over bcrypt's limit in bytes", strings.Repeat("ä", 36) + "1", []string{tooLong}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := passwordPolicyViolations(tt.password); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("passwordPolicyViolations(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyViolationsConfigured(t *testing.T) {
+	requireDigit := false
+	useConfig(t, &common.Configuration{Password: common.PasswordPolicyConfiguration{MinLength: 4, RequireDigit: &requireDigit}})
+
+	tests := []struct {
+		password string
+		want     []string
+	}{
+		{"abc", []string{tooShort4}},
+		{"abcd", []string{}},
+		{"1234", []string{noLetter}},
+	}
+	for _, tt := range tests {
+		if got := passwordPolicyViolations(tt.password); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("passwordPolicyViolations(%q) = %v, want %v", tt.password, got, tt.want)
+		}
+	}
+}
+
+func TestAddNewCustomerRejectsWeakPassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useConfig(t, nil)
+	router := gin.New()
+	router.POST("/v1/customersignup", AddNewCustomer)
+
+	body := `{"name":"Jane","email_address":"jane@example.com","phonenumber":"+14155552671","password":"a"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/customersignup", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body.String())
+	}
+	var response struct {
+		Details struct {
+			Requirements []string `json:"requirements"`
+		} `json:"details"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if want := []string{tooShort, noDigit}; !reflect.DeepEqual(response.Details.Requirements, want) {
+		t.Fatalf("requirements %v, want %v", response.Details.Requirements, want)
+	}
+}
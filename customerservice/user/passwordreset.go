@@ -0,0 +1,145 @@
+package user
+
+import (
+	common "customerservice/common"
+	database "customerservice/database"
+	models "customerservice/models"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/martian/log"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// resetTokenTTL is how long a password reset token stays valid
+const resetTokenTTL = 30 * time.Minute
+
+// errInvalidResetToken is returned when a reset token is unknown, used or expired
+var errInvalidResetToken = errors.New("invalid or expired reset token")
+
+// @Summary Request a password reset
+// @Description Issue a single-use reset token for the account, if it exists
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param request body models.ForgotPasswordModel true "Account email"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} common.ErrorResponse
+// @Router /customer/forgot-password [post]
+func ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordModel
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("JSON binding error %v", err)
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	// The response is identical whether or not the email exists
+	response := gin.H{"message": "if the account exists, a password reset link has been sent"}
+
+	db := database.GetDB()
+
+	var customer models.CustomerDetail
+	if err := db.Where("email_address = ?", req.EmailAddress).First(&customer).Error; err != nil {
+		c.IndentedJSON(http.StatusOK, response)
+		return
+	}
+
+	token, err := newOpaqueToken()
+	if err != nil {
+		log.Errorf("reset token generation error %v", err)
+		c.IndentedJSON(http.StatusOK, response)
+		return
+	}
+
+	resetToken := models.PasswordResetToken{
+		CustomerId: customer.CustomerId,
+		TokenHash:  hashToken(token),
+		ExpiresAt:  time.Now().Add(resetTokenTTL),
+	}
+	if err := db.Create(&resetToken).Error; err != nil {
+		log.Errorf("DB create error %v", err)
+		c.IndentedJSON(http.StatusOK, response)
+		return
+	}
+
+	// There is no mailer yet, so the token is only logged for operators
+	log.Infof("password reset token for customer %d: %s (expires %s)", customer.CustomerId, token, resetToken.ExpiresAt.Format(time.RFC3339))
+
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+// @Summary Reset password
+// @Description Set a new password using a valid reset token
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param request body models.ResetPasswordModel true "Reset token and new password"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} common.ErrorResponse
+// @Failure 500 {object} common.ErrorResponse
+// @Router /customer/reset-password [post]
+func ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordModel
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("JSON binding error %v", err)
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if msg := passwordStrengthError(req.NewPassword); msg != "" {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, msg)
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		log.Errorf("password hash error %v", err.Error())
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Error processing password")
+		return
+	}
+
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var resetToken models.PasswordResetToken
+		if err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashToken(req.Token), time.Now()).
+			First(&resetToken).Error; err != nil {
+			return errInvalidResetToken
+		}
+
+		now := time.Now()
+
+		// Burn this token and any other outstanding ones for the customer
+		if err := tx.Model(&models.PasswordResetToken{}).
+			Where("customer_id = ? AND used_at IS NULL", resetToken.CustomerId).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		if err := revokeRefreshTokens(tx, resetToken.CustomerId); err != nil {
+			return err
+		}
+
+		// Bumping the token version signs out sessions that used the old password
+		return tx.Model(&models.CustomerDetail{}).
+			Where("customer_id = ?", resetToken.CustomerId).
+			Updates(map[string]interface{}{
+				"password":      string(hashedPassword),
+				"token_version": gorm.Expr("token_version + 1"),
+			}).Error
+	})
+
+	if errors.Is(err, errInvalidResetToken) {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "invalid or expired reset token")
+		return
+	}
+	if err != nil {
+		log.Errorf("password reset error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Error saving password")
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "password reset successfully, please log in again"})
+}
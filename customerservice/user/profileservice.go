@@ -0,0 +1,126 @@
+package user
+
+import (
+	auth "customerservice/auth"
+	common "customerservice/common"
+	database "customerservice/database"
+	models "customerservice/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/martian/log"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// @Summary Get customer profile
+// @Description Return the authenticated customer's details
+// @Tags user
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} models.CustomerProfile
+// @Failure 401 {object} common.ErrorResponse
+// @Failure 404 {object} common.ErrorResponse
+// @Router /customer/profile [get]
+func GetCustomerProfile(c *gin.Context) {
+	customerId, err := auth.CustomerId(c)
+	if err != nil {
+		common.RespondError(c, http.StatusUnauthorized, common.CodeUnauthorized, "invalid token")
+		return
+	}
+
+	var customer models.CustomerDetail
+	if err := database.GetDB().Where("customer_id = ?", customerId).First(&customer).Error; err != nil {
+		log.Errorf("DB query error %v", err)
+		common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "customer not found")
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, models.CustomerProfile{
+		CustomerId:   customer.CustomerId,
+		Name:         customer.Name,
+		EmailAddress: customer.EmailAddress,
+		PhoneNumber:  customer.PhoneNumber,
+	})
+}
+
+// @Summary Change password
+// @Description Change the authenticated customer's password and sign out existing sessions
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param passwords body models.ChangePasswordModel true "Old and new passwords"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} common.ErrorResponse
+// @Failure 401 {object} common.ErrorResponse
+// @Failure 500 {object} common.ErrorResponse
+// @Router /customer/change-password [post]
+func ChangePassword(c *gin.Context) {
+	customerId, err := auth.CustomerId(c)
+	if err != nil {
+		common.RespondError(c, http.StatusUnauthorized, common.CodeUnauthorized, "invalid token")
+		return
+	}
+
+	var req models.ChangePasswordModel
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("JSON binding error %v", err)
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if msg := passwordStrengthError(req.NewPassword); msg != "" {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, msg)
+		return
+	}
+
+	db := database.GetDB()
+
+	var customer models.CustomerDetail
+	if err := db.Where("customer_id = ?", customerId).First(&customer).Error; err != nil {
+		log.Errorf("DB query error %v", err)
+		common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "customer not found")
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(customer.Password), []byte(req.OldPassword)); err != nil {
+		common.RespondError(c, http.StatusUnauthorized, common.CodeUnauthorized, "invalid credentials")
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		log.Errorf("password hash error %v", err.Error())
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Error processing password")
+		return
+	}
+
+	// Bumping the token version invalidates every access token issued with the old password,
+	// and revoking refresh tokens stops them from minting new ones
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&customer).Updates(map[string]interface{}{
+			"password":      string(hashedPassword),
+			"token_version": customer.TokenVersion + 1,
+		}).Error; err != nil {
+			return err
+		}
+		return revokeRefreshTokens(tx, customer.CustomerId)
+	})
+	if err != nil {
+		log.Errorf("DB update error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Error saving password")
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "password changed successfully, please log in again"})
+}
+
+// CurrentTokenVersion returns the customer's token version for the auth middleware
+func CurrentTokenVersion(customerId int) (int, error) {
+	var customer models.CustomerDetail
+	if err := database.GetDB().Select("token_version").Where("customer_id = ?", customerId).First(&customer).Error; err != nil {
+		return 0, err
+	}
+	return customer.TokenVersion, nil
+}
@@ -0,0 +1,140 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	common "customerservice/common"
+	database "customerservice/database"
+	models "customerservice/models"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/martian/log"
+	"gorm.io/gorm"
+)
+
+const (
+	// accessTokenTTL is the lifetime of a signed access token
+	accessTokenTTL = 15 * time.Minute
+	// refreshTokenTTL is the lifetime of a server-side refresh token
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// @Summary Refresh access token
+// @Description Exchange a valid refresh token for a new access token
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param request body models.RefreshTokenModel true "Refresh token"
+// @Success 200 {object} models.TokenResponse
+// @Failure 400 {object} common.ErrorResponse
+// @Failure 401 {object} common.ErrorResponse
+// @Failure 500 {object} common.ErrorResponse
+// @Router /customer/refresh [post]
+func RefreshAccessToken(c *gin.Context) {
+	var req models.RefreshTokenModel
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("JSON binding error %v", err)
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	db := database.GetDB()
+
+	var refreshToken models.RefreshToken
+	if err := db.Where("token_hash = ? AND revoked_at IS NULL AND expires_at > ?", hashToken(req.RefreshToken), time.Now()).
+		First(&refreshToken).Error; err != nil {
+		common.RespondError(c, http.StatusUnauthorized, common.CodeUnauthorized, "invalid refresh token")
+		return
+	}
+
+	var customer models.CustomerDetail
+	if err := db.Where("customer_id = ?", refreshToken.CustomerId).First(&customer).Error; err != nil {
+		common.RespondError(c, http.StatusUnauthorized, common.CodeUnauthorized, "invalid refresh token")
+		return
+	}
+
+	tokenString, err := issueAccessToken(customer)
+	if err != nil {
+		log.Errorf("token sign error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "could not create token")
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"access_token": tokenString,
+		"expires_in":   int(accessTokenTTL.Seconds()),
+	})
+}
+
+// @Summary Log out
+// @Description Revoke a refresh token so it can no longer mint access tokens
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param request body models.RefreshTokenModel true "Refresh token"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} common.ErrorResponse
+// @Failure 500 {object} common.ErrorResponse
+// @Router /customer/logout [post]
+func Logout(c *gin.Context) {
+	var req models.RefreshTokenModel
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("JSON binding error %v", err)
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	// Unknown or already revoked tokens are treated as logged out
+	if err := database.GetDB().Model(&models.RefreshToken{}).
+		Where("token_hash = ? AND revoked_at IS NULL", hashToken(req.RefreshToken)).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		log.Errorf("DB update error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "could not log out")
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "logged out successfully"})
+}
+
+// issueRefreshToken creates and stores a new refresh token for the customer
+func issueRefreshToken(db *gorm.DB, customerId int) (string, error) {
+	token, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	refreshToken := models.RefreshToken{
+		CustomerId: customerId,
+		TokenHash:  hashToken(token),
+		ExpiresAt:  time.Now().Add(refreshTokenTTL),
+	}
+	if err := db.Create(&refreshToken).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// revokeRefreshTokens revokes every active refresh token of a customer
+func revokeRefreshTokens(db *gorm.DB, customerId int) error {
+	return db.Model(&models.RefreshToken{}).
+		Where("customer_id = ? AND revoked_at IS NULL", customerId).
+		Update("revoked_at", time.Now()).Error
+}
+
+// newOpaqueToken returns a random 256-bit token encoded as hex
+func newOpaqueToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// hashToken returns the hex SHA-256 of a token as stored in the database
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	common "inventoryservice/common"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Verification mirrors customerservice/auth so tokens issued at login are accepted here.
+
+// RoleKey is the gin context key holding the authenticated caller's role
+const RoleKey = "role"
+
+// SubjectKey is the gin context key holding the authenticated caller's token subject
+const SubjectKey = "subject"
+
+// RoleAdmin is the role allowed to perform destructive inventory operations
+const RoleAdmin = "admin"
+
+// secret is the HS256 key shared with the customer service
+var secret string
+
+// LoadSecret reads JWT_SECRET from the environment and fails when it is unset
+func LoadSecret() error {
+	value := os.Getenv("JWT_SECRET")
+	if value == "" {
+		return errors.New("JWT_SECRET environment variable is not set")
+	}
+	secret = value
+	return nil
+}
+
+// AuthRequired rejects requests without a valid "Authorization: Bearer <jwt>" header
+func AuthRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !found || strings.TrimSpace(tokenString) == "" {
+			common.AbortWithError(c, http.StatusUnauthorized, common.CodeUnauthorized, "missing bearer token")
+			return
+		}
+
+		role, subject, err := verifyToken(strings.TrimSpace(tokenString))
+		if err != nil {
+			common.AbortWithError(c, http.StatusUnauthorized, common.CodeUnauthorized, "invalid token")
+			return
+		}
+
+		c.Set(RoleKey, role)
+		c.Set(SubjectKey, subject)
+		c.Next()
+	}
+}
+
+// RequireRole rejects authenticated requests whose token does not carry the given role.
+// It must run after AuthRequired.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(RoleKey) != role {
+			common.AbortWithError(c, http.StatusForbidden, common.CodeForbidden, "insufficient permissions")
+			return
+		}
+		c.Next()
+	}
+}
+
+// Actor identifies who is making a request for audit fields: the token subject when the caller sent
+// a valid bearer token, otherwise the configured system actor used for internal calls.
+func Actor(c *gin.Context) string {
+	if subject := c.GetString(SubjectKey); subject != "" {
+		return subject
+	}
+	if tokenString, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); found {
+		if _, subject, err := verifyToken(strings.TrimSpace(tokenString)); err == nil && subject != "" {
+			return subject
+		}
+	}
+	return common.SystemActor()
+}
+
+// verifyToken checks an HS256 token's signature and expiry and returns its role and subject claims
+func verifyToken(tokenString string) (string, string, error) {
+	if secret == "" {
+		return "", "", errors.New("JWT secret is not configured")
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return "", "", err
+	}
+
+	// Customer tokens carry the numeric customer ID as "sub", which decodes as a float64
+	subject := ""
+	switch sub := claims["sub"].(type) {
+	case float64:
+		subject = strconv.FormatFloat(sub, 'f', -1, 64)
+	case string:
+		subject = sub
+	}
+
+	role, _ := claims["role"].(string)
+	return role, subject, nil
+}
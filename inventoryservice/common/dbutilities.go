@@ -1,6 +1,8 @@
 package common
 
 import (
+	"time"
+
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
@@ -8,7 +10,15 @@ import (
 var Config *Configuration
 
 type Configuration struct {
-	Database DatabaseConfiguration
+	Database    DatabaseConfiguration
+	Reservation ReservationConfiguration
+	Cors        CorsConfiguration
+	Webhook     WebhookConfiguration
+	Audit       AuditConfiguration
+	Payment     PaymentConfiguration
+	Reset       ResetConfiguration
+	Outbox      OutboxConfiguration
+	Request     RequestConfiguration
 }
 
 type DatabaseConfiguration struct {
@@ -21,6 +31,78 @@ type DatabaseConfiguration struct {
 	MaxLifetime  int
 	MaxOpenConns int
 	MaxIdleConns int
+	// ConnectAttempts and ConnectBackoff control the startup retry; the backoff doubles after each failure
+	ConnectAttempts int
+	ConnectBackoff  time.Duration
+	// QueryTimeout bounds the request-scoped queries of the heavy read endpoints
+	QueryTimeout time.Duration
+}
+
+type ReservationConfiguration struct {
+	Ttl                    time.Duration
+	CleanupIntervalSeconds int
+	CleanupEnabled         *bool
+	// RoutingPolicy picks the warehouse when a reservation doesn't name one: most_stock, nearest or fewest_warehouses
+	RoutingPolicy string
+	// ExpiryGrace keeps a lapsed reservation shippable, and out of cleanup's reach, for this long past expires_at
+	ExpiryGrace time.Duration
+	// AvailableSoonWindow is how far ahead CheckAvailability looks for held stock about to expire
+	AvailableSoonWindow time.Duration
+}
+
+// WebhookConfiguration is the optional endpoint told when reservations expire, ship or are released
+type WebhookConfiguration struct {
+	Url     string
+	Retries int
+}
+
+// OutboxConfiguration paces the outbox publisher; MaxAttempts is the default before an event is marked FAILED
+type OutboxConfiguration struct {
+	Interval    time.Duration
+	MaxAttempts int
+}
+
+// ResetConfiguration guards POST /v1/admin/reset, which wipes every table; only enable it for e2e runs
+type ResetConfiguration struct {
+	Allowed bool
+}
+
+// PaymentConfiguration locates the payment service; RequirePayment makes ship refuse unpaid orders
+type PaymentConfiguration struct {
+	Url            string
+	RequirePayment bool
+}
+
+// AuditConfiguration names the actor recorded for calls that carry no authenticated user
+type AuditConfiguration struct {
+	SystemActor string
+}
+
+// RequestConfiguration caps request body sizes in bytes; MaxUploadBytes applies to multipart uploads
+type RequestConfiguration struct {
+	MaxBodyBytes   int64
+	MaxUploadBytes int64
+}
+
+// CorsConfiguration lists the browser origins allowed to call the API; empty denies all
+type CorsConfiguration struct {
+	AllowedOrigins []string
+}
+
+// databaseEnvBindings maps each database setting to the env vars that override it; the first one set wins
+var databaseEnvBindings = map[string][]string{
+	"database.driver":          {"DB_DRIVER"},
+	"database.host":            {"DB_HOST"},
+	"database.port":            {"DB_PORT"},
+	"database.dbname":          {"DB_NAME"},
+	"database.username":        {"DB_USER"},
+	"database.password":        {"DB_PASSWORD", "APP_DB_PASSWORD"},
+	"database.maxlifetime":     {"DB_MAX_LIFETIME"},
+	"database.maxopenconns":    {"DB_MAX_OPEN_CONNS"},
+	"database.maxidleconns":    {"DB_MAX_IDLE_CONNS"},
+	"database.connectattempts": {"DB_CONNECT_ATTEMPTS"},
+	"database.connectbackoff":  {"DB_CONNECT_BACKOFF"},
+	"database.querytimeout":    {"DB_QUERY_TIMEOUT"},
 }
 
 func ConfigSetup(configPath string) error {
@@ -34,6 +116,38 @@ func ConfigSetup(configPath string) error {
 		return err
 	}
 
+	_ = viper.BindEnv("webhook.url", "RESERVATION_WEBHOOK_URL")
+
+	// Database settings resolve as env var, then config file, then default
+	for key, envs := range databaseEnvBindings {
+		_ = viper.BindEnv(append([]string{key}, envs...)...)
+	}
+	viper.SetDefault("database.driver", "postgres")
+	viper.SetDefault("database.host", "postgres_main")
+	viper.SetDefault("database.port", "5432")
+
+	// Comma-separated origins, e.g. "https://admin.example.com,http://localhost:5173"
+	_ = viper.BindEnv("cors.allowedorigins", "CORS_ALLOWED_ORIGINS")
+
+	_ = viper.BindEnv("request.maxbodybytes", "MAX_BODY_BYTES")
+	_ = viper.BindEnv("request.maxuploadbytes", "MAX_UPLOAD_BYTES")
+
+	_ = viper.BindEnv("reservation.routingpolicy", "RESERVATION_ROUTING_POLICY")
+	_ = viper.BindEnv("reservation.availablesoonwindow", "AVAILABLE_SOON_WINDOW")
+
+	// Whether ship checks for a completed payment is decided per deployment
+	_ = viper.BindEnv("payment.url", "PAYMENT_SERVICE_URL")
+	_ = viper.BindEnv("payment.requirepayment", "REQUIRE_PAYMENT")
+
+	viper.SetDefault("audit.systemactor", "system")
+	_ = viper.BindEnv("audit.systemactor", "AUDIT_SYSTEM_ACTOR")
+
+	_ = viper.BindEnv("outbox.interval", "OUTBOX_INTERVAL")
+	_ = viper.BindEnv("outbox.maxattempts", "OUTBOX_MAX_ATTEMPTS")
+
+	// Table resets stay off unless an e2e environment opts in
+	_ = viper.BindEnv("reset.allowed", "ALLOW_RESET")
+
 	err := viper.Unmarshal(&configuration)
 	if err != nil {
 		log.Fatalf("Unable to decode into struct, %v", err)
@@ -47,3 +161,11 @@ func ConfigSetup(configPath string) error {
 func GetConfig() *Configuration {
 	return Config
 }
+
+// SystemActor is recorded as the creator of records made without an authenticated user
+func SystemActor() string {
+	if Config != nil && Config.Audit.SystemActor != "" {
+		return Config.Audit.SystemActor
+	}
+	return "system"
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"time"
+
+	common "inventoryservice/common"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultConnectAttempts = 10
+	defaultConnectBackoff  = 1 * time.Second
+	maxConnectBackoff      = 30 * time.Second
+)
+
+// openWithRetry opens the database, retrying with exponential backoff so the service
+// can start before Postgres is accepting connections (e.g. under docker-compose)
+func openWithRetry(dialector gorm.Dialector, config *gorm.Config, settings common.DatabaseConfiguration) (*gorm.DB, error) {
+	attempts := settings.ConnectAttempts
+	if attempts <= 0 {
+		attempts = defaultConnectAttempts
+	}
+	backoff := settings.ConnectBackoff
+	if backoff <= 0 {
+		backoff = defaultConnectBackoff
+	}
+
+	var db *gorm.DB
+	var openErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		db, openErr = gorm.Open(dialector, config)
+		if openErr == nil {
+			log.Infof("Connected to DB on attempt %d/%d", attempt, attempts)
+			return db, nil
+		}
+		if attempt == attempts {
+			break
+		}
+
+		log.Warnf("Failed to connect to DB (attempt %d/%d): %v; retrying in %s", attempt, attempts, openErr, backoff)
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+
+	log.Errorf("Could not connect to DB after %d attempts: %v", attempts, openErr)
+	return nil, openErr
+}
@@ -3,7 +3,6 @@ package database
 import (
 	common "inventoryservice/common"
 	models "inventoryservice/models"
-	"os"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -16,6 +15,9 @@ var (
 	err  error
 )
 
+// defaultHost is used when neither the config file nor DB_HOST names a database host
+const defaultHost = "postgres_main"
+
 type Repository struct {
 	Database *gorm.DB
 }
@@ -36,20 +38,16 @@ func SetupDB(configuration *common.Configuration) error {
 	if host != "" {
 		log.Info("Host IP is ", host)
 	} else {
-		log.Error("Host is Empty in Env Variable")
-	}
-
-	pw := os.Getenv("APP_DB_PASSWORD")
-	if pw != "" {
-		password = pw
+		host = defaultHost
+		log.Warn("Host is Empty in config and DB_HOST, falling back to ", host)
 	}
 
 	// data source name
 	dsn := "host=" + host + " user=" + username + " password=" + password + " port=" + port + " dbname=" + dbname
 	if driver == "postgres" { // Postgres DB
-		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		db, err = openWithRetry(postgres.Open(dsn), &gorm.Config{}, configuration.Database)
 		if err != nil {
-			log.Error("db err: ", err)
+			return err
 		}
 	}
 
@@ -70,7 +68,15 @@ func SetupDB(configuration *common.Configuration) error {
 
 // Auto migrate project models
 func migrateModels() {
-	err = Repo.Database.AutoMigrate(&models.InventoryModel{}, &models.ReservationRecord{})
+	// Idempotency keys used to be unique per reservation row; batches now share a key across rows
+	migrator := Repo.Database.Migrator()
+	if migrator.HasIndex(&models.ReservationRecord{}, "idx_reservation_records_idempotency_key") {
+		if dropErr := migrator.DropIndex(&models.ReservationRecord{}, "idx_reservation_records_idempotency_key"); dropErr != nil {
+			log.Error("Dropping old reservation index failed: ", dropErr)
+		}
+	}
+
+	err = Repo.Database.AutoMigrate(&models.InventoryModel{}, &models.ReservationRecord{}, &models.InventoryReceipt{}, &models.Warehouse{}, &OutboxEvent{})
 	if err != nil {
 		log.Error("Auto-migrate error: ", err)
 	}
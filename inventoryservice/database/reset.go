@@ -0,0 +1,74 @@
+package database
+
+import (
+	common "inventoryservice/common"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// ResetTables returns a handler that truncates the tables of the given models and restarts their IDs,
+// so end-to-end runs start from empty state. It answers 403 unless reset.allowed (ALLOW_RESET) is set.
+func ResetTables(service string, models ...interface{}) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !resetAllowed(c, service) {
+			return
+		}
+		if Repo.Database == nil {
+			common.RespondError(c, http.StatusServiceUnavailable, common.CodeInternal, "database is not initialized")
+			return
+		}
+
+		tables := make([]string, 0, len(models))
+		quoted := make([]string, 0, len(models))
+		for _, model := range models {
+			stmt := &gorm.Statement{DB: Repo.Database}
+			if err := stmt.Parse(model); err != nil {
+				log.Errorf("Failed to resolve table for reset: %v", err)
+				common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Reset failed")
+				return
+			}
+			tables = append(tables, stmt.Schema.Table)
+			quoted = append(quoted, stmt.Quote(stmt.Schema.Table))
+		}
+
+		log.WithField("service", service).Warnf("RESETTING DATABASE: truncating %s", strings.Join(tables, ", "))
+		if err := Repo.Database.Exec("TRUNCATE TABLE " + strings.Join(quoted, ", ") + " RESTART IDENTITY CASCADE").Error; err != nil {
+			log.Errorf("Database reset failed: %v", err)
+			common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Reset failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Database reset",
+			"service": service,
+			"tables":  tables,
+		})
+	}
+}
+
+// RequireReset guards other destructive routes, such as seed endpoints that clear or overwrite tables,
+// with the same reset.allowed (ALLOW_RESET) flag as ResetTables
+func RequireReset(service string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !resetAllowed(c, service) {
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// resetAllowed answers 403 and reports false unless reset.allowed (ALLOW_RESET) is set
+func resetAllowed(c *gin.Context, service string) bool {
+	config := common.GetConfig()
+	if config == nil || !config.Reset.Allowed {
+		log.WithField("service", service).Warnf("Refused %s %s: ALLOW_RESET is not enabled", c.Request.Method, c.Request.URL.Path)
+		common.RespondError(c, http.StatusForbidden, common.CodeForbidden, "Reset is disabled")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"context"
+	"errors"
+	common "inventoryservice/common"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// defaultQueryTimeout is used when database.querytimeout (DB_QUERY_TIMEOUT) is unset
+const defaultQueryTimeout = 5 * time.Second
+
+// RequestDB returns the database bound to the request's context plus the configured query timeout, so its
+// queries are cancelled when the client disconnects or the deadline passes. Call cancel when done.
+func RequestDB(c *gin.Context) (*gorm.DB, context.CancelFunc) {
+	timeout := defaultQueryTimeout
+	if config := common.GetConfig(); config != nil && config.Database.QueryTimeout > 0 {
+		timeout = config.Database.QueryTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	return GetDB().WithContext(ctx), cancel
+}
+
+// RespondIfTimedOut answers 503 when a RequestDB query failed because it was cancelled or ran past its
+// deadline, and reports whether it did so the caller can fall back to its usual error response
+func RespondIfTimedOut(c *gin.Context, err error) bool {
+	if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	log.Warnf("Query for %s %s cancelled: %v", c.Request.Method, c.FullPath(), err)
+	common.RespondError(c, http.StatusServiceUnavailable, common.CodeTimeout, "Database query timed out")
+	return true
+}
@@ -1,76 +1,125 @@
 package inventory
 
 import (
+	"context"
+	common "inventoryservice/common"
 	database "inventoryservice/database"
 	models "inventoryservice/models"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
-// CleanupExpiredReservations is a background job that releases expired reservations
-func CleanupExpiredReservations() {
-	log.Info("Starting reservation cleanup job")
+// cleanupRunning is set while the cleanup job runs, so the status endpoint reports whether it is disabled or stopped
+var cleanupRunning atomic.Bool
+
+// CleanupExpiredReservations is a background job that releases expired reservations until ctx is cancelled
+func CleanupExpiredReservations(ctx context.Context, interval time.Duration) {
+	log.Infof("Starting reservation cleanup job (interval %s)", interval)
+
+	cleanupRunning.Store(true)
+	defer cleanupRunning.Store(false)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
 	for {
-		db := database.GetDB()
-
-		// Find expired reservations
-		var expiredReservations []models.ReservationRecord
-		if err := db.Where("status = ? AND expires_at < ?", "RESERVED", time.Now()).Find(&expiredReservations).Error; err != nil {
-			log.Errorf("Error finding expired reservations: %v", err)
-			time.Sleep(1 * time.Minute)
-			continue
+		cleanupExpiredReservationsOnce()
+
+		// Wait for the next cleanup cycle or shutdown
+		select {
+		case <-ctx.Done():
+			log.Info("Reservation cleanup job stopped")
+			return
+		case <-ticker.C:
 		}
+	}
+}
 
-		if len(expiredReservations) > 0 {
-			log.Infof("Found %d expired reservations to clean up", len(expiredReservations))
+// cleanupExpiredReservationsOnce runs a single cleanup pass. Each reservation is expired in its own
+// transaction, so one failure leaves only that reservation for the next run.
+func cleanupExpiredReservationsOnce() {
+	db := database.GetDB()
+	cutoff := time.Now().Add(-reservationGrace())
 
-			tx := db.Begin()
+	// Find reservations expired beyond the grace period; inside it an in-flight payment may still ship them
+	var expiredReservations []models.ReservationRecord
+	if err := db.Where("status = ? AND expires_at < ?", "RESERVED", cutoff).Find(&expiredReservations).Error; err != nil {
+		log.Errorf("Error finding expired reservations: %v", err)
+		return
+	}
 
-			for _, reservation := range expiredReservations {
-				// Find inventory record
-				var inventory models.InventoryModel
-				if err := tx.Where("product_id = ? AND warehouse = ?",
-					reservation.ProductId, reservation.Warehouse).First(&inventory).Error; err != nil {
-					log.Errorf("Inventory record not found for reservation %d: %v", reservation.ID, err)
-					continue
-				}
+	if len(expiredReservations) == 0 {
+		return
+	}
 
-				// Release reserved quantity back to available stock
-				inventory.Reserved -= reservation.Quantity
-				inventory.UpdatedAt = time.Now()
+	log.Infof("Found %d expired reservations to clean up", len(expiredReservations))
 
-				if err := tx.Save(&inventory).Error; err != nil {
-					log.Errorf("Failed to release inventory for reservation %d: %v", reservation.ID, err)
-					continue
-				}
+	for _, reservation := range expiredReservations {
+		if err := expireReservation(db, reservation, cutoff); err != nil {
+			log.Errorf("Failed to expire reservation %d, leaving it for the next run: %v", reservation.ID, err)
+		}
+	}
+}
 
-				// Update reservation status
-				reservation.Status = "EXPIRED"
-				reservation.UpdatedAt = time.Now()
+// expireReservation marks a reservation EXPIRED and releases its stock. The status only changes if the
+// reservation is still RESERVED and lapsed, so one confirmed or shipped since it was read is left alone.
+func expireReservation(db *gorm.DB, reservation models.ReservationRecord, cutoff time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.ReservationRecord{}).
+			Where("id = ? AND status = ? AND expires_at < ?", reservation.ID, "RESERVED", cutoff).
+			Update("status", "EXPIRED")
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			log.Infof("Reservation %d changed state before cleanup reached it; skipping", reservation.ID)
+			return nil
+		}
+		reservation.Status = "EXPIRED"
 
-				if err := tx.Save(&reservation).Error; err != nil {
-					log.Errorf("Failed to update reservation %d: %v", reservation.ID, err)
-					continue
-				}
+		// Find inventory record
+		var inventory models.InventoryModel
+		if err := tx.Where("product_id = ? AND ware_house = ?",
+			reservation.ProductId, reservation.Warehouse).First(&inventory).Error; err != nil {
+			return err
+		}
 
-				log.Infof("Released expired reservation %d: product %d, quantity %d, warehouse %s",
-					reservation.ID, reservation.ProductId, reservation.Quantity, reservation.Warehouse)
-			}
+		// Release reserved quantity back to available stock
+		releaseReserved(&inventory, reservation)
 
-			tx.Commit()
+		if err := saveInventory(tx, &inventory); err != nil {
+			return err
 		}
 
-		// Sleep for 1 minute before next cleanup cycle
-		time.Sleep(1 * time.Minute)
-	}
+		if err := enqueueReservationEvents(tx, EventReservationExpired, []models.ReservationRecord{reservation}, ""); err != nil {
+			return err
+		}
+
+		log.Infof("Released expired reservation %d: product %d, quantity %d, warehouse %s",
+			reservation.ID, reservation.ProductId, reservation.Quantity, reservation.Warehouse)
+		return nil
+	})
 }
 
-// StartCleanupJob starts the background cleanup job
-func StartCleanupJob() {
-	go CleanupExpiredReservations()
+// StartCleanupJob starts the background cleanup job using the configured interval, unless it is disabled.
+// The job runs until ctx is cancelled.
+func StartCleanupJob(ctx context.Context) {
+	interval := 1 * time.Minute
+	if config := common.GetConfig(); config != nil {
+		if config.Reservation.CleanupEnabled != nil && !*config.Reservation.CleanupEnabled {
+			log.Info("Reservation cleanup job disabled by configuration")
+			return
+		}
+		if config.Reservation.CleanupIntervalSeconds > 0 {
+			interval = time.Duration(config.Reservation.CleanupIntervalSeconds) * time.Second
+		}
+	}
+
+	go CleanupExpiredReservations(ctx, interval)
 	log.Info("Reservation cleanup job started")
 }
 
@@ -80,13 +129,15 @@ func GetReservationStatus(c *gin.Context) {
 
 	// Count reservations by status
 	var stats struct {
-		ActiveReservations   int64 `json:"active_reservations"`
-		ExpiredReservations  int64 `json:"expired_reservations"`
-		ShippedReservations  int64 `json:"shipped_reservations"`
-		ReleasedReservations int64 `json:"released_reservations"`
+		ActiveReservations    int64 `json:"active_reservations"`
+		ConfirmedReservations int64 `json:"confirmed_reservations"`
+		ExpiredReservations   int64 `json:"expired_reservations"`
+		ShippedReservations   int64 `json:"shipped_reservations"`
+		ReleasedReservations  int64 `json:"released_reservations"`
 	}
 
 	db.Model(&models.ReservationRecord{}).Where("status = ?", "RESERVED").Count(&stats.ActiveReservations)
+	db.Model(&models.ReservationRecord{}).Where("status = ?", "CONFIRMED").Count(&stats.ConfirmedReservations)
 	db.Model(&models.ReservationRecord{}).Where("status = ?", "EXPIRED").Count(&stats.ExpiredReservations)
 	db.Model(&models.ReservationRecord{}).Where("status = ?", "SHIPPED").Count(&stats.ShippedReservations)
 	db.Model(&models.ReservationRecord{}).Where("status = ?", "RELEASED").Count(&stats.ReleasedReservations)
@@ -97,8 +148,22 @@ func GetReservationStatus(c *gin.Context) {
 		"RESERVED", time.Now(), time.Now().Add(1*time.Hour)).Count(&expiringSoon)
 
 	c.JSON(200, gin.H{
-		"reservation_stats":  stats,
-		"expiring_in_1_hour": expiringSoon,
-		"cleanup_active":     true,
+		"reservation_stats":    stats,
+		"expiring_in_1_hour":   expiringSoon,
+		"cleanup_active":       cleanupRunning.Load(),
+		"expiry_grace_seconds": int64(reservationGrace().Seconds()),
 	})
 }
+
+// releaseReserved removes a reservation's quantity from the inventory's reserved count.
+// If the data is inconsistent and less is reserved than the reservation holds, reserved is
+// clamped to zero and a warning is logged rather than storing a negative value.
+func releaseReserved(inventory *models.InventoryModel, reservation models.ReservationRecord) {
+	if inventory.Reserved < reservation.Quantity {
+		log.Warnf("Reservation %d holds %d units of product %d in %s but only %d are reserved; clamping reserved to 0",
+			reservation.ID, reservation.Quantity, reservation.ProductId, reservation.Warehouse, inventory.Reserved)
+		inventory.Reserved = 0
+		return
+	}
+	inventory.Reserved -= reservation.Quantity
+}
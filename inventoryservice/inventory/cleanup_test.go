@@ -0,0 +1,96 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	dbtest "inventoryservice/database/dbtest"
+	models "inventoryservice/models"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCleanupExpiredReservationsOnce(t *testing.T) {
+	db := dbtest.Open(t, testModels...)
+	inventory := createInventory(t, db, models.InventoryModel{ProductId: 1, WareHouse: "WH1", OnHand: 10, Reserved: 6})
+
+	now := time.Now()
+	reservations := []models.ReservationRecord{
+		{ProductId: 1, Warehouse: "WH1", Quantity: 3, OrderId: "ORD-1", IdempotencyKey: "expired", Status: "RESERVED", ReservedAt: now.Add(-time.Hour), ExpiresAt: now.Add(-time.Minute)},
+		{ProductId: 1, Warehouse: "WH1", Quantity: 2, OrderId: "ORD-2", IdempotencyKey: "active", Status: "RESERVED", ReservedAt: now, ExpiresAt: now.Add(time.Hour)},
+		{ProductId: 1, Warehouse: "WH1", Quantity: 1, OrderId: "ORD-3", IdempotencyKey: "confirmed", Status: "CONFIRMED", ReservedAt: now.Add(-time.Hour), ExpiresAt: now.Add(-time.Minute)},
+	}
+	if err := db.Create(&reservations).Error; err != nil {
+		t.Fatalf("create reservations: %v", err)
+	}
+
+	cleanupExpiredReservationsOnce()
+
+	if got := loadInventory(t, db, inventory.InventoryId); got.Reserved != 3 || got.OnHand != 10 {
+		t.Fatalf("got on_hand %d reserved %d, want on_hand 10 reserved 3", got.OnHand, got.Reserved)
+	}
+
+	want := map[string]string{"expired": "EXPIRED", "active": "RESERVED", "confirmed": "CONFIRMED"}
+	for key, status := range want {
+		var reservation models.ReservationRecord
+		if err := db.First(&reservation, "idempotency_key = ?", key).Error; err != nil {
+			t.Fatalf("load reservation %s: %v", key, err)
+		}
+		if reservation.Status != status {
+			t.Errorf("reservation %s is %s, want %s", key, reservation.Status, status)
+		}
+	}
+
+	// A second pass finds nothing left to expire and must not release the stock again
+	cleanupExpiredReservationsOnce()
+	if got := loadInventory(t, db, inventory.InventoryId); got.Reserved != 3 {
+		t.Fatalf("reserved is %d after a second pass, want 3", got.Reserved)
+	}
+}
+
+func TestReservationStatusReportsCleanupJob(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbtest.Open(t, testModels...)
+	router := gin.New()
+	router.GET("/v1/inventory/reservations/status", GetReservationStatus)
+
+	cleanupActive := func() bool {
+		t.Helper()
+		w := sendJSON(router, http.MethodGet, "/v1/inventory/reservations/status", "")
+		var response struct {
+			CleanupActive bool `json:"cleanup_active"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		return response.CleanupActive
+	}
+
+	if cleanupActive() {
+		t.Fatal("cleanup_active is true before the job started")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		CleanupExpiredReservations(ctx, time.Hour)
+		close(stopped)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cleanupActive() {
+		if time.Now().After(deadline) {
+			cancel()
+			t.Fatal("cleanup_active is still false after the job started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	<-stopped
+	if cleanupActive() {
+		t.Fatal("cleanup_active is true after the job stopped")
+	}
+}
@@ -2,11 +2,15 @@ package inventory
 
 import (
 	"encoding/csv"
+	"errors"
+	auth "inventoryservice/auth"
+	common "inventoryservice/common"
 	database "inventoryservice/database"
 	models "inventoryservice/models"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -14,6 +18,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/martian/log"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func AddInventory(c *gin.Context) {
@@ -21,13 +26,13 @@ func AddInventory(c *gin.Context) {
 	err := c.ShouldBind(&inventoryModel)
 	if err != nil {
 		log.Errorf("FORM binding error %v", err.Error())
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err})
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, err.Error())
 		return
 	}
 
 	tx := database.GetDB().Create(&inventoryModel)
 	if tx.Error != nil {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Error saving data"})
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Error saving data")
 		return
 	}
 
@@ -39,7 +44,7 @@ func UpdateInventory(c *gin.Context) {
 	err := c.ShouldBind(&inventoryModel)
 	if err != nil {
 		log.Errorf("FORM binding error %v", err.Error())
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err})
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, err.Error())
 		return
 	}
 
@@ -49,21 +54,44 @@ func UpdateInventory(c *gin.Context) {
 	t := database.Where("inventory_id=?", inventoryModel.InventoryId).First(&existingInventoryDetail)
 	if t.Error != nil {
 		log.Errorf("DB query error %v", t.Error)
-		c.IndentedJSON(http.StatusNotFound, gin.H{"message": t.Error})
+		if errors.Is(t.Error, gorm.ErrRecordNotFound) {
+			common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "Inventory not found")
+			return
+		}
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to fetch inventory")
 		return
 	}
 
-	existingInventoryDetail.ProductId = inventoryModel.ProductId
-	existingInventoryDetail.WareHouse = inventoryModel.WareHouse
-	existingInventoryDetail.OnHand = inventoryModel.OnHand
-	existingInventoryDetail.Reserved = inventoryModel.Reserved
-	existingInventoryDetail.UpdatedAt = time.Now()
+	// Fields left out of the request keep their stored values
+	if inventoryModel.ProductId != 0 {
+		existingInventoryDetail.ProductId = inventoryModel.ProductId
+	}
+	if inventoryModel.WareHouse != "" {
+		existingInventoryDetail.WareHouse = inventoryModel.WareHouse
+	}
+	if inventoryModel.OnHand != 0 {
+		existingInventoryDetail.OnHand = inventoryModel.OnHand
+	}
+	if inventoryModel.Reserved != 0 {
+		existingInventoryDetail.Reserved = inventoryModel.Reserved
+	}
+	if inventoryModel.ReorderPoint != 0 {
+		existingInventoryDetail.ReorderPoint = inventoryModel.ReorderPoint
+	}
+
+	// A client that sends the version it read only overwrites that version of the row
+	if inventoryModel.Version != 0 {
+		existingInventoryDetail.Version = inventoryModel.Version
+	}
 
 	log.Infof(existingInventoryDetail.WareHouse)
 
-	tx := database.Model(&existingInventoryDetail).Updates(existingInventoryDetail)
-	if tx.Error != nil {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Error saving data"})
+	if err := saveInventory(database, &existingInventoryDetail); err != nil {
+		if errors.Is(err, errInventoryConflict) {
+			common.RespondError(c, http.StatusConflict, common.CodeConflict, "Inventory was modified concurrently, reload and retry")
+			return
+		}
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Error saving data")
 		return
 	}
 
@@ -71,10 +99,16 @@ func UpdateInventory(c *gin.Context) {
 }
 
 func DeleteInventory(c *gin.Context) {
-	inventoryId, err := strconv.Atoi(c.Query("inventoryId"))
+	// Try to get ID from URL parameter first, then query parameter
+	inventoryIdStr := c.Param("id")
+	if inventoryIdStr == "" {
+		inventoryIdStr = c.Query("inventoryId")
+	}
+
+	inventoryId, err := strconv.Atoi(inventoryIdStr)
 	if err != nil {
 		log.Errorf("Invalid inventory ID: %v", err)
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Invalid inventory ID"})
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid inventory ID")
 		return
 	}
 
@@ -84,13 +118,17 @@ func DeleteInventory(c *gin.Context) {
 	t := database.Where("inventory_id=?", inventoryId).First(&existingInventoryDetail)
 	if t.Error != nil {
 		log.Errorf("DB query error %v", t.Error)
-		c.IndentedJSON(http.StatusNotFound, gin.H{"message": t.Error})
+		if errors.Is(t.Error, gorm.ErrRecordNotFound) {
+			common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "Inventory not found")
+			return
+		}
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to fetch inventory")
 		return
 	}
 
 	tx := database.Model(&existingInventoryDetail).Delete(existingInventoryDetail)
 	if tx.Error != nil {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Error saving data"})
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Error saving data")
 		return
 	}
 
@@ -98,10 +136,16 @@ func DeleteInventory(c *gin.Context) {
 }
 
 func GetInventoryById(c *gin.Context) {
-	inventoryId, err := strconv.Atoi(c.Query("inventoryId"))
+	// Try to get ID from URL parameter first, then query parameter
+	inventoryIdStr := c.Param("id")
+	if inventoryIdStr == "" {
+		inventoryIdStr = c.Query("inventoryId")
+	}
+
+	inventoryId, err := strconv.Atoi(inventoryIdStr)
 	if err != nil {
 		log.Errorf("Invalid inventory ID: %v", err)
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Invalid inventory ID"})
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid inventory ID")
 		return
 	}
 
@@ -111,40 +155,59 @@ func GetInventoryById(c *gin.Context) {
 	t := database.Where("inventory_id=?", inventoryId).First(&existingInventoryDetail)
 	if t.Error != nil {
 		log.Errorf("DB query error %v", t.Error)
-		c.IndentedJSON(http.StatusNotFound, gin.H{"message": t.Error})
+		if errors.Is(t.Error, gorm.ErrRecordNotFound) {
+			common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "Inventory not found")
+			return
+		}
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to fetch inventory")
 		return
 	}
 
 	c.IndentedJSON(http.StatusOK, existingInventoryDetail)
 }
 
+// GetAllInventory lists inventory rows a page at a time, optionally filtered by product and warehouse
 func GetAllInventory(c *gin.Context) {
-	var inventoryDetails []models.InventoryModel
-	database := database.GetDB()
+	limit, offset := common.Paginate(c)
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit := 10
-	offset := (page - 1) * limit
+	db, cancel := database.RequestDB(c)
+	defer cancel()
 
-	// var inventoryModel models.InventoryModel
-	// err := c.ShouldBind(&inventoryModel)
-	// if err != nil {
-	// 	log.Errorf("FORM binding error %v", err.Error())
-	// 	c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err})
-	// 	return
-	// }
+	query := db.Model(&models.InventoryModel{})
+	if p := c.Query("product_id"); p != "" {
+		productId, err := strconv.Atoi(p)
+		if err != nil {
+			common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "product_id must be an integer")
+			return
+		}
+		query = query.Where("product_id = ?", productId)
+	}
+	if warehouse := strings.TrimSpace(c.Query("warehouse")); warehouse != "" {
+		query = query.Where("ware_house = ?", warehouse)
+	}
 
-	// t := database.Where("product_id LIKE ?", "%"+inventoryModel.ProductId+"%").
-	// 	Offset(offset).Limit(limit).Find(&inventoryDetails)
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		if database.RespondIfTimedOut(c, err) {
+			return
+		}
+		log.Errorf("DB count error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to list inventory")
+		return
+	}
 
-	t := database.Offset(offset).Limit(limit).Find(&inventoryDetails)
+	var inventoryDetails []models.InventoryModel
+	t := query.Order("inventory_id asc").Offset(offset).Limit(limit).Find(&inventoryDetails)
 	if t.Error != nil {
+		if database.RespondIfTimedOut(c, t.Error) {
+			return
+		}
 		log.Errorf("DB query error %v", t.Error)
-		c.IndentedJSON(http.StatusNotFound, gin.H{"message": t.Error})
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to list inventory")
 		return
 	}
 
-	c.IndentedJSON(http.StatusOK, inventoryDetails)
+	c.IndentedJSON(http.StatusOK, common.NewPageResponse(inventoryDetails, total, limit, offset).WithLegacyKey("inventory", len(inventoryDetails)))
 }
 
 func SeedInventoryDetail(c *gin.Context) {
@@ -153,17 +216,22 @@ func SeedInventoryDetail(c *gin.Context) {
 	db := database.GetDB()
 	if del := db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.InventoryModel{}); del.Error != nil {
 		log.Errorf("DB delete error: %v", del.Error)
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Error clearing inventory table"})
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Error clearing inventory table")
 		return
 	}
 
 	log.Infof("Cleared existing inventory data")
 
+	// Inventory rows refer to warehouses by code, so load the master list first
+	if _, err := seedWarehouses(db); err != nil {
+		log.Errorf("Warehouse seed failed: %v", err)
+	}
+
 	csvPath := filepath.Join("seeddata", "eci_inventory.csv")
 	f, err := os.Open(csvPath)
 	if err != nil {
 		log.Errorf("Cannot open seed file: %v", err)
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Cannot open seed file"})
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Cannot open seed file")
 		return
 	}
 	defer f.Close()
@@ -173,11 +241,11 @@ func SeedInventoryDetail(c *gin.Context) {
 	records, err := r.ReadAll()
 	if err != nil {
 		log.Errorf("CSV read error: %v", err)
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "CSV read error"})
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "CSV read error")
 		return
 	}
 	if len(records) < 2 {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "CSV contains no data"})
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "CSV contains no data")
 		return
 	}
 
@@ -230,6 +298,14 @@ func SeedInventoryDetail(c *gin.Context) {
 			}
 		}
 
+		if v, ok := idx["reorder_point"]; ok && v < len(row) {
+			if s := strings.TrimSpace(row[v]); s != "" {
+				if n, e := strconv.Atoi(s); e == nil {
+					m.ReorderPoint = n
+				}
+			}
+		}
+
 		if v, ok := idx["updated_at"]; ok && v < len(row) {
 			if s := strings.TrimSpace(row[v]); s != "" {
 				// try common timestamp layouts
@@ -245,17 +321,11 @@ func SeedInventoryDetail(c *gin.Context) {
 				// 	parsed, perr = time.Parse("2006-01-02", s)
 				// }
 
+				// otherwise GORM stamps the current time on insert
 				if perr == nil {
 					m.UpdatedAt = parsed
-				} else {
-					// fallback
-					m.UpdatedAt = time.Now()
 				}
-			} else {
-				m.UpdatedAt = time.Now()
 			}
-		} else {
-			m.UpdatedAt = time.Now()
 		}
 
 		tx := db.Create(&m)
@@ -269,171 +339,642 @@ func SeedInventoryDetail(c *gin.Context) {
 	c.IndentedJSON(http.StatusOK, gin.H{"inserted": inserted})
 }
 
-// ReserveInventory reserves inventory for an order with TTL (15 minutes)
+// ReserveInventory reserves inventory for an order with the configured TTL (15 minutes by default)
 func ReserveInventory(c *gin.Context) {
 	var req models.ReservationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid request", err.Error())
 		return
 	}
 
-	db := database.GetDB()
+	if dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false")); err != nil {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "dry_run must be true or false")
+		return
+	} else if dryRun {
+		req.DryRun = true
+	}
 
-	// Check for duplicate reservation with same idempotency key
-	var existingReservation models.ReservationRecord
-	if err := db.Where("idempotency_key = ?", req.IdempotencyKey).First(&existingReservation).Error; err == nil {
-		// Return existing reservation
-		c.JSON(http.StatusOK, gin.H{
-			"message":     "Reservation already exists",
-			"reservation": existingReservation,
-			"idempotent":  true,
-		})
+	// A dry run never stores anything, so it needs no idempotency key
+	req.IdempotencyKey = common.ResolveIdempotencyKey(c, req.IdempotencyKey)
+	if req.IdempotencyKey == "" && !req.DryRun {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "idempotency_key or an Idempotency-Key header is required")
 		return
 	}
 
+	db := database.GetDB()
+
+	// Check for duplicate reservation with same idempotency key; a dry run reports current availability instead
+	if !req.DryRun {
+		var existingReservations []models.ReservationRecord
+		if err := db.Where("idempotency_key = ?", req.IdempotencyKey).Find(&existingReservations).Error; err == nil && len(existingReservations) > 0 {
+			// A reused key only replays when the payload matches what was reserved under it
+			if !replayMatches(existingReservations, req) {
+				common.RespondErrorWithDetails(c, http.StatusConflict, common.CodeIdempotencyKeyReuse, "Idempotency key was already used for a different reservation", gin.H{
+					"product_id": existingReservations[0].ProductId,
+					"order_id":   existingReservations[0].OrderId,
+					"quantity":   reservedQuantity(existingReservations),
+				})
+				return
+			}
+
+			// Return existing reservation
+			response := gin.H{
+				"message":    "Reservation already exists",
+				"idempotent": true,
+			}
+			if len(existingReservations) == 1 {
+				response["reservation"] = existingReservations[0]
+			} else {
+				response["reservations"] = existingReservations
+			}
+			c.JSON(http.StatusOK, response)
+			return
+		}
+	}
+
+	if req.Warehouse != "" {
+		if err := validateWarehouse(db, req.Warehouse); err != nil {
+			respondWarehouseError(c, req.Warehouse, err)
+			return
+		}
+	}
+
+	route := requestRouting(req.Region)
+
 	// Start transaction for atomic reservation
 	tx := db.Begin()
 
-	// Find inventory to reserve from (try specific warehouse first, then any)
-	var inventoryItems []models.InventoryModel
-	query := "product_id = ? AND (on_hand - reserved) >= ?"
-	args := []interface{}{req.ProductId, req.Quantity}
+	reservation, err := reserveItem(tx, req.ProductId, req.Quantity, req.Warehouse, route, req.OrderId, req.IdempotencyKey, auth.Actor(c))
 
-	if req.Warehouse != "" {
-		query += " AND ware_house = ?"
-		args = append(args, req.Warehouse)
+	// Fall back to spreading the quantity over several warehouses when allowed
+	if errors.Is(err, errInsufficientInventory) && req.AllowSplit && req.Warehouse == "" {
+		reservations, splitErr := splitReservation(tx, req.ProductId, req.Quantity, route, req.OrderId, req.IdempotencyKey, auth.Actor(c))
+		if splitErr == nil && req.DryRun {
+			tx.Rollback()
+			respondDryRun(c, route, reservations)
+			return
+		}
+		if splitErr == nil {
+			tx.Commit()
+
+			c.JSON(http.StatusOK, gin.H{
+				"message":        "Inventory reserved across multiple warehouses",
+				"reservations":   reservations,
+				"split":          true,
+				"routing_policy": route.Policy,
+				"expires_at":     reservations[0].ExpiresAt,
+			})
+			return
+		}
+		err = splitErr
 	}
-	query += " ORDER BY ware_house, on_hand DESC"
 
-	if err := tx.Where(query, args...).Find(&inventoryItems).Error; err != nil {
+	if err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		if errors.Is(err, errInsufficientInventory) {
+			common.RespondErrorWithDetails(c, http.StatusConflict, common.CodeInsufficientInventory, "Insufficient inventory", gin.H{
+				"product_id": req.ProductId,
+				"requested":  req.Quantity,
+			})
+			return
+		}
+		if errors.Is(err, errInventoryConflict) {
+			common.RespondError(c, http.StatusConflict, common.CodeConflict, "Inventory was modified concurrently, please retry")
+			return
+		}
+		log.Errorf("Reservation failed for product %d: %v", req.ProductId, err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to reserve inventory")
 		return
 	}
 
-	if len(inventoryItems) == 0 {
+	if req.DryRun {
 		tx.Rollback()
-		c.JSON(http.StatusConflict, gin.H{
-			"error":      "Insufficient inventory",
-			"product_id": req.ProductId,
-			"requested":  req.Quantity,
+		respondDryRun(c, route, []models.ReservationRecord{reservation})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Inventory reserved successfully",
+		"reservation":    reservation,
+		"warehouse":      reservation.Warehouse,
+		"routing_policy": route.Policy,
+		"expires_at":     reservation.ExpiresAt,
+	})
+}
+
+// replayMatches reports whether the reservations stored under an idempotency key were made for the same
+// product, order and total quantity as req. A split reservation matches when its parts add up to the quantity.
+func replayMatches(existing []models.ReservationRecord, req models.ReservationRequest) bool {
+	for _, reservation := range existing {
+		if reservation.ProductId != req.ProductId || reservation.OrderId != req.OrderId {
+			return false
+		}
+	}
+	if req.Warehouse != "" && (len(existing) != 1 || existing[0].Warehouse != req.Warehouse) {
+		return false
+	}
+	return reservedQuantity(existing) == req.Quantity
+}
+
+// reservedQuantity sums the quantity held across reservation rows
+func reservedQuantity(reservations []models.ReservationRecord) int {
+	total := 0
+	for _, reservation := range reservations {
+		total += reservation.Quantity
+	}
+	return total
+}
+
+// respondDryRun reports the reservations a dry run would have made; they were rolled back, so no stock is held
+func respondDryRun(c *gin.Context, route routing, reservations []models.ReservationRecord) {
+	warehouses := make([]gin.H, 0, len(reservations))
+	for _, reservation := range reservations {
+		warehouses = append(warehouses, gin.H{
+			"warehouse": reservation.Warehouse,
+			"quantity":  reservation.Quantity,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Reservation would succeed",
+		"dry_run":        true,
+		"warehouse":      reservations[0].Warehouse,
+		"warehouses":     warehouses,
+		"split":          len(reservations) > 1,
+		"routing_policy": route.Policy,
+	})
+}
+
+// ReserveInventoryBatch reserves every item of an order in one transaction, or none of them
+func ReserveInventoryBatch(c *gin.Context) {
+	var req models.BatchReservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid request", err.Error())
+		return
+	}
+
+	req.IdempotencyKey = common.ResolveIdempotencyKey(c, req.IdempotencyKey)
+	if req.IdempotencyKey == "" {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "idempotency_key or an Idempotency-Key header is required")
+		return
+	}
+
+	// Each product/warehouse pair may appear only once per batch
+	seen := make(map[string]bool, len(req.Items))
+	for _, item := range req.Items {
+		key := strconv.Itoa(item.ProductId) + "|" + item.Warehouse
+		if seen[key] {
+			common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Duplicate item in batch", gin.H{"product_id": item.ProductId})
+			return
+		}
+		seen[key] = true
+	}
+
+	db := database.GetDB()
+
+	// Check for an existing batch with the same idempotency key
+	var existingReservations []models.ReservationRecord
+	if err := db.Where("idempotency_key = ?", req.IdempotencyKey).Find(&existingReservations).Error; err == nil && len(existingReservations) > 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "Reservation already exists",
+			"reservations": existingReservations,
+			"idempotent":   true,
 		})
 		return
 	}
 
+	for _, item := range req.Items {
+		if item.Warehouse == "" {
+			continue
+		}
+		if err := validateWarehouse(db, item.Warehouse); err != nil {
+			respondWarehouseError(c, item.Warehouse, err)
+			return
+		}
+	}
+
+	route := requestRouting(req.Region)
+	tx := db.Begin()
+
+	// Keep the items that don't name a warehouse together when one warehouse can ship them all
+	if route.Policy == RoutingFewestWarehouses {
+		unassigned := make([]models.BatchReservationItem, 0, len(req.Items))
+		for _, item := range req.Items {
+			if item.Warehouse == "" {
+				unassigned = append(unassigned, item)
+			}
+		}
+		if len(unassigned) > 1 {
+			warehouse, err := warehouseCoveringAll(tx, unassigned)
+			if err != nil {
+				tx.Rollback()
+				log.Errorf("Warehouse routing failed for order %s: %v", req.OrderId, err)
+				common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to reserve inventory")
+				return
+			}
+			for i := range req.Items {
+				if req.Items[i].Warehouse == "" {
+					req.Items[i].Warehouse = warehouse
+				}
+			}
+		}
+	}
+
+	results := make([]gin.H, 0, len(req.Items))
+	reservations := make([]models.ReservationRecord, 0, len(req.Items))
+	actor := auth.Actor(c)
+
+	for _, item := range req.Items {
+		reservation, err := reserveItem(tx, item.ProductId, item.Quantity, item.Warehouse, route, req.OrderId, req.IdempotencyKey, actor)
+		if err != nil {
+			tx.Rollback()
+			results = append(results, gin.H{
+				"product_id": item.ProductId,
+				"requested":  item.Quantity,
+				"status":     "FAILED",
+				"error":      err.Error(),
+			})
+			if errors.Is(err, errInsufficientInventory) {
+				common.RespondErrorWithDetails(c, http.StatusConflict, common.CodeInsufficientInventory, "Insufficient inventory", gin.H{"results": results})
+				return
+			}
+			if errors.Is(err, errInventoryConflict) {
+				common.RespondErrorWithDetails(c, http.StatusConflict, common.CodeConflict, "Inventory was modified concurrently, please retry", gin.H{"results": results})
+				return
+			}
+			common.RespondErrorWithDetails(c, http.StatusInternalServerError, common.CodeInternal, "Failed to reserve inventory", gin.H{"results": results})
+			return
+		}
+
+		reservations = append(reservations, reservation)
+		results = append(results, gin.H{
+			"product_id":     reservation.ProductId,
+			"quantity":       reservation.Quantity,
+			"warehouse":      reservation.Warehouse,
+			"reservation_id": reservation.ID,
+			"status":         reservation.Status,
+		})
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Inventory reserved successfully",
+		"results":        results,
+		"reservations":   reservations,
+		"routing_policy": route.Policy,
+		"expires_at":     reservations[0].ExpiresAt,
+	})
+}
+
+// errInsufficientInventory is returned when no warehouse can cover a reservation
+var errInsufficientInventory = errors.New("insufficient inventory")
+
+// reserveItem holds stock for one product inside tx and records the reservation with the configured TTL.
+// Without a warehouse the route picks among those with enough stock.
+func reserveItem(tx *gorm.DB, productId int, quantity int, warehouse string, route routing, orderId string, idempotencyKey string, createdBy string) (models.ReservationRecord, error) {
+	// Find inventory to reserve from (try specific warehouse first, then any)
+	var inventoryItems []models.InventoryModel
+	query := "product_id = ? AND (on_hand - reserved) >= ?"
+	args := []interface{}{productId, quantity}
+
+	if warehouse != "" {
+		query += " AND ware_house = ?"
+		args = append(args, warehouse)
+	}
+
+	// Lock the candidate rows so concurrent reservations wait instead of overselling
+	if err := route.order(tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(query, args...)).
+		Find(&inventoryItems).Error; err != nil {
+		return models.ReservationRecord{}, errors.New("database error")
+	}
+
 	// Reserve from the first available warehouse with sufficient stock
 	var selectedItem *models.InventoryModel
 	for i := range inventoryItems {
-		if inventoryItems[i].OnHand-inventoryItems[i].Reserved >= req.Quantity {
+		if inventoryItems[i].OnHand-inventoryItems[i].Reserved >= quantity {
 			selectedItem = &inventoryItems[i]
 			break
 		}
 	}
 
 	if selectedItem == nil {
-		tx.Rollback()
-		c.JSON(http.StatusConflict, gin.H{
-			"error":      "Insufficient inventory",
-			"product_id": req.ProductId,
-			"requested":  req.Quantity,
-		})
-		return
+		return models.ReservationRecord{}, errInsufficientInventory
 	}
 
 	// Update inventory reserved count
-	selectedItem.Reserved += req.Quantity
-	selectedItem.UpdatedAt = time.Now()
+	selectedItem.Reserved += quantity
 
-	if err := tx.Save(selectedItem).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reserve inventory"})
-		return
+	if err := saveInventory(tx, selectedItem); err != nil {
+		if errors.Is(err, errInventoryConflict) {
+			return models.ReservationRecord{}, err
+		}
+		return models.ReservationRecord{}, errors.New("failed to reserve inventory")
 	}
 
 	// Create reservation record with 15-minute TTL
 	reservation := models.ReservationRecord{
-		ProductId:      req.ProductId,
+		ProductId:      productId,
 		Warehouse:      selectedItem.WareHouse,
-		Quantity:       req.Quantity,
-		OrderId:        req.OrderId,
-		IdempotencyKey: req.IdempotencyKey,
+		Quantity:       quantity,
+		OrderId:        orderId,
+		IdempotencyKey: idempotencyKey,
 		Status:         "RESERVED",
+		CreatedBy:      createdBy,
 		ReservedAt:     time.Now(),
-		ExpiresAt:      time.Now().Add(15 * time.Minute),
-		UpdatedAt:      time.Now(),
+		ExpiresAt:      time.Now().Add(reservationTTL()),
 	}
 
 	if err := tx.Create(&reservation).Error; err != nil {
+		return models.ReservationRecord{}, errors.New("failed to create reservation record")
+	}
+
+	return reservation, nil
+}
+
+// splitReservation spreads a reservation over as many warehouses as needed, one record per warehouse
+func splitReservation(tx *gorm.DB, productId int, quantity int, route routing, orderId string, idempotencyKey string, createdBy string) ([]models.ReservationRecord, error) {
+	// Lock every warehouse row with spare stock, in route order (largest availability first by default)
+	var inventoryItems []models.InventoryModel
+	if err := route.order(tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_id = ? AND (on_hand - reserved) > 0", productId)).
+		Find(&inventoryItems).Error; err != nil {
+		return nil, errors.New("database error")
+	}
+
+	totalAvailable := 0
+	for _, item := range inventoryItems {
+		totalAvailable += item.OnHand - item.Reserved
+	}
+	if totalAvailable < quantity {
+		return nil, errInsufficientInventory
+	}
+
+	reservations := make([]models.ReservationRecord, 0, len(inventoryItems))
+	remaining := quantity
+
+	for i := range inventoryItems {
+		if remaining == 0 {
+			break
+		}
+
+		item := &inventoryItems[i]
+		take := item.OnHand - item.Reserved
+		if take > remaining {
+			take = remaining
+		}
+
+		item.Reserved += take
+
+		if err := saveInventory(tx, item); err != nil {
+			if errors.Is(err, errInventoryConflict) {
+				return nil, err
+			}
+			return nil, errors.New("failed to reserve inventory")
+		}
+
+		reservation := models.ReservationRecord{
+			ProductId:      productId,
+			Warehouse:      item.WareHouse,
+			Quantity:       take,
+			OrderId:        orderId,
+			IdempotencyKey: idempotencyKey,
+			Status:         "RESERVED",
+			CreatedBy:      createdBy,
+			ReservedAt:     time.Now(),
+			ExpiresAt:      time.Now().Add(reservationTTL()),
+		}
+
+		if err := tx.Create(&reservation).Error; err != nil {
+			return nil, errors.New("failed to create reservation record")
+		}
+
+		reservations = append(reservations, reservation)
+		remaining -= take
+	}
+
+	return reservations, nil
+}
+
+// ExtendReservation pushes back the expiry of an order's active reservation by the configured TTL
+func ExtendReservation(c *gin.Context) {
+	var req models.ExtendReservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid request", err.Error())
+		return
+	}
+
+	db := database.GetDB()
+	tx := db.Begin()
+
+	var reservations []models.ReservationRecord
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("idempotency_key = ? AND order_id = ?", req.IdempotencyKey, req.OrderId).
+		Find(&reservations).Error; err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reservation record"})
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Database error")
 		return
 	}
 
+	if len(reservations) == 0 {
+		tx.Rollback()
+		common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "Reservation not found")
+		return
+	}
+
+	for _, reservation := range reservations {
+		if reservation.Status != "RESERVED" {
+			tx.Rollback()
+			common.RespondErrorWithDetails(c, http.StatusConflict, common.CodeConflict, "Only active reservations can be extended", gin.H{
+				"status": reservation.Status,
+			})
+			return
+		}
+	}
+
+	// Extend from the current expiry, or from now if the hold has lapsed but not been swept yet
+	now := time.Now()
+	for i := range reservations {
+		base := reservations[i].ExpiresAt
+		if base.Before(now) {
+			base = now
+		}
+		reservations[i].ExpiresAt = base.Add(reservationTTL())
+
+		if err := tx.Save(&reservations[i]).Error; err != nil {
+			tx.Rollback()
+			common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to extend reservation")
+			return
+		}
+	}
+
 	tx.Commit()
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":     "Inventory reserved successfully",
-		"reservation": reservation,
-		"warehouse":   selectedItem.WareHouse,
-		"expires_at":  reservation.ExpiresAt,
+		"message":      "Reservation extended successfully",
+		"reservations": reservations,
+		"expires_at":   reservations[0].ExpiresAt,
 	})
 }
 
+// reservationTTL returns how long a reservation holds stock before it expires
+func reservationTTL() time.Duration {
+	if config := common.GetConfig(); config != nil && config.Reservation.Ttl > 0 {
+		return config.Reservation.Ttl
+	}
+	return 15 * time.Minute
+}
+
+// reservationGrace returns how long past expires_at a reservation can still be confirmed or shipped
+func reservationGrace() time.Duration {
+	if config := common.GetConfig(); config != nil && config.Reservation.ExpiryGrace > 0 {
+		return config.Reservation.ExpiryGrace
+	}
+	return 0
+}
+
+// reservationLapsed reports whether a held reservation is past its expiry and the grace period after it
+func reservationLapsed(reservation models.ReservationRecord, now time.Time) bool {
+	return reservation.Status == "RESERVED" && now.After(reservation.ExpiresAt.Add(reservationGrace()))
+}
+
+// respondIfLapsed rejects a confirm or ship when any reservation has expired beyond the grace period,
+// since cleanup may already be releasing its stock
+func respondIfLapsed(c *gin.Context, reservations []models.ReservationRecord) bool {
+	now := time.Now()
+	for _, reservation := range reservations {
+		if reservationLapsed(reservation, now) {
+			common.RespondErrorWithDetails(c, http.StatusConflict, common.CodeConflict, "Reservation has expired", gin.H{
+				"expires_at":           reservation.ExpiresAt,
+				"expiry_grace_seconds": int64(reservationGrace().Seconds()),
+			})
+			return true
+		}
+	}
+	return false
+}
+
 // ReleaseInventory releases reserved inventory back to available stock
 func ReleaseInventory(c *gin.Context) {
 	var req models.ReleaseRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid request", err.Error())
 		return
 	}
 
 	db := database.GetDB()
 	tx := db.Begin()
 
-	// Find reservation record
-	var reservation models.ReservationRecord
-	if err := tx.Where("idempotency_key = ? AND order_id = ? AND status = ?",
-		req.IdempotencyKey, req.OrderId, "RESERVED").First(&reservation).Error; err != nil {
+	// Find reservation records; split reservations have one row per warehouse.
+	// Confirmed reservations can still be released if the order is cancelled.
+	var reservations []models.ReservationRecord
+	if err := tx.Where("idempotency_key = ? AND order_id = ? AND status IN ?",
+		req.IdempotencyKey, req.OrderId, []string{"RESERVED", "CONFIRMED"}).Find(&reservations).Error; err != nil || len(reservations) == 0 {
 		tx.Rollback()
-		c.JSON(http.StatusNotFound, gin.H{"error": "Reservation not found or already processed"})
+		common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "Reservation not found or already processed")
 		return
 	}
 
-	// Find inventory record
-	var inventory models.InventoryModel
-	if err := tx.Where("product_id = ? AND ware_house = ?",
-		reservation.ProductId, reservation.Warehouse).First(&inventory).Error; err != nil {
+	releasedQuantity := 0
+	for i := range reservations {
+		reservation := &reservations[i]
+
+		// Find inventory record
+		var inventory models.InventoryModel
+		if err := tx.Where("product_id = ? AND ware_house = ?",
+			reservation.ProductId, reservation.Warehouse).First(&inventory).Error; err != nil {
+			tx.Rollback()
+			common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Inventory record not found")
+			return
+		}
+
+		// Release reserved quantity back to available stock
+		releaseReserved(&inventory, *reservation)
+
+		if err := saveInventory(tx, &inventory); err != nil {
+			tx.Rollback()
+			if errors.Is(err, errInventoryConflict) {
+				common.RespondError(c, http.StatusConflict, common.CodeConflict, "Inventory was modified concurrently, please retry")
+				return
+			}
+			common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to release inventory")
+			return
+		}
+
+		// Update reservation status
+		reservation.Status = "RELEASED"
+
+		if err := tx.Save(reservation).Error; err != nil {
+			tx.Rollback()
+			common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to update reservation record")
+			return
+		}
+
+		releasedQuantity += reservation.Quantity
+	}
+
+	if err := enqueueReservationEvents(tx, EventReservationReleased, reservations, common.RequestId(c)); err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Inventory record not found"})
+		log.Errorf("Failed to record release events for order %s: %v", req.OrderId, err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to release inventory")
 		return
 	}
 
-	// Release reserved quantity back to available stock
-	inventory.Reserved -= reservation.Quantity
-	inventory.UpdatedAt = time.Now()
+	tx.Commit()
 
-	if err := tx.Save(&inventory).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release inventory"})
+	c.JSON(http.StatusOK, gin.H{
+		"message":           "Inventory released successfully",
+		"reservations":      reservations,
+		"released_quantity": releasedQuantity,
+	})
+}
+
+// ConfirmInventory commits a reservation to a paid order so it no longer expires; stock stays reserved
+func ConfirmInventory(c *gin.Context) {
+	var req models.ConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid request", err.Error())
 		return
 	}
 
-	// Update reservation status
-	reservation.Status = "RELEASED"
-	reservation.UpdatedAt = time.Now()
+	db := database.GetDB()
+	tx := db.Begin()
+
+	// Find reservation records; split reservations have one row per warehouse
+	var reservations []models.ReservationRecord
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("idempotency_key = ? AND order_id = ? AND status = ?", req.IdempotencyKey, req.OrderId, "RESERVED").
+		Find(&reservations).Error; err != nil || len(reservations) == 0 {
+		tx.Rollback()
+		common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "Reservation not found or already processed")
+		return
+	}
 
-	if err := tx.Save(&reservation).Error; err != nil {
+	if respondIfLapsed(c, reservations) {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reservation record"})
 		return
 	}
 
+	confirmedQuantity := 0
+	for i := range reservations {
+		reservations[i].Status = "CONFIRMED"
+
+		if err := tx.Save(&reservations[i]).Error; err != nil {
+			tx.Rollback()
+			common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to update reservation record")
+			return
+		}
+		confirmedQuantity += reservations[i].Quantity
+	}
+
 	tx.Commit()
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":           "Inventory released successfully",
-		"reservation":       reservation,
-		"released_quantity": reservation.Quantity,
+		"message":            "Reservation confirmed successfully",
+		"reservations":       reservations,
+		"confirmed_quantity": confirmedQuantity,
 	})
 }
 
@@ -441,49 +982,85 @@ func ReleaseInventory(c *gin.Context) {
 func ShipInventory(c *gin.Context) {
 	var req models.ShipRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid request", err.Error())
 		return
 	}
 
+	// With payment required, stock only leaves the warehouse for orders the payment service shows as paid
+	if required, paymentUrl := paymentRequired(); required {
+		paid, err := orderPaid(paymentUrl, req.OrderId, common.RequestId(c))
+		if err != nil {
+			log.Errorf("Payment check failed for order %s: %v", req.OrderId, err)
+			common.RespondError(c, http.StatusBadGateway, common.CodeUpstream, "Unable to verify payment for order")
+			return
+		}
+		if !paid {
+			common.RespondErrorWithDetails(c, http.StatusConflict, common.CodeConflict, "Order has no completed payment", gin.H{"order_id": req.OrderId})
+			return
+		}
+	}
+
 	db := database.GetDB()
 	tx := db.Begin()
 
-	// Find reservation record
-	var reservation models.ReservationRecord
-	if err := tx.Where("idempotency_key = ? AND order_id = ? AND status = ?",
-		req.IdempotencyKey, req.OrderId, "RESERVED").First(&reservation).Error; err != nil {
+	// Find reservation records; split reservations have one row per warehouse.
+	// Both held and confirmed reservations can ship.
+	var reservations []models.ReservationRecord
+	if err := tx.Where("idempotency_key = ? AND order_id = ? AND status IN ?",
+		req.IdempotencyKey, req.OrderId, []string{"RESERVED", "CONFIRMED"}).Find(&reservations).Error; err != nil || len(reservations) == 0 {
 		tx.Rollback()
-		c.JSON(http.StatusNotFound, gin.H{"error": "Reservation not found or already processed"})
+		common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "Reservation not found or already processed")
 		return
 	}
 
-	// Find inventory record
-	var inventory models.InventoryModel
-	if err := tx.Where("product_id = ? AND ware_house = ?",
-		reservation.ProductId, reservation.Warehouse).First(&inventory).Error; err != nil {
+	if respondIfLapsed(c, reservations) {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Inventory record not found"})
 		return
 	}
 
-	// Ship: reduce both on_hand and reserved quantities
-	inventory.OnHand -= reservation.Quantity
-	inventory.Reserved -= reservation.Quantity
-	inventory.UpdatedAt = time.Now()
+	shippedQuantity := 0
+	for i := range reservations {
+		reservation := &reservations[i]
+
+		// Find inventory record
+		var inventory models.InventoryModel
+		if err := tx.Where("product_id = ? AND ware_house = ?",
+			reservation.ProductId, reservation.Warehouse).First(&inventory).Error; err != nil {
+			tx.Rollback()
+			common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Inventory record not found")
+			return
+		}
 
-	if err := tx.Save(&inventory).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to ship inventory"})
-		return
-	}
+		// Ship: reduce both on_hand and reserved quantities
+		inventory.OnHand -= reservation.Quantity
+		releaseReserved(&inventory, *reservation)
+
+		if err := saveInventory(tx, &inventory); err != nil {
+			tx.Rollback()
+			if errors.Is(err, errInventoryConflict) {
+				common.RespondError(c, http.StatusConflict, common.CodeConflict, "Inventory was modified concurrently, please retry")
+				return
+			}
+			common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to ship inventory")
+			return
+		}
+
+		// Update reservation status
+		reservation.Status = "SHIPPED"
 
-	// Update reservation status
-	reservation.Status = "SHIPPED"
-	reservation.UpdatedAt = time.Now()
+		if err := tx.Save(reservation).Error; err != nil {
+			tx.Rollback()
+			common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to update reservation record")
+			return
+		}
 
-	if err := tx.Save(&reservation).Error; err != nil {
+		shippedQuantity += reservation.Quantity
+	}
+
+	if err := enqueueReservationEvents(tx, EventReservationShipped, reservations, common.RequestId(c)); err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reservation record"})
+		log.Errorf("Failed to record ship events for order %s: %v", req.OrderId, err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to ship inventory")
 		return
 	}
 
@@ -491,28 +1068,207 @@ func ShipInventory(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":          "Inventory shipped successfully",
-		"reservation":      reservation,
-		"shipped_quantity": reservation.Quantity,
+		"reservations":     reservations,
+		"shipped_quantity": shippedQuantity,
 	})
 }
 
-// CheckAvailability checks product availability across warehouses
+// reservationView is a reservation with the seconds left before it expires
+type reservationView struct {
+	models.ReservationRecord
+	RemainingSeconds int64 `json:"remaining_seconds"`
+	// InGrace marks a hold past expires_at that can still be confirmed or shipped
+	InGrace bool `json:"in_grace"`
+}
+
+// GetReservationsByOrder returns every reservation held for an order
+func GetReservationsByOrder(c *gin.Context) {
+	orderId := c.Param("orderId")
+
+	var reservations []models.ReservationRecord
+	if err := database.GetDB().Where("order_id = ?", orderId).Order("reserved_at ASC").Find(&reservations).Error; err != nil {
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Database error")
+		return
+	}
+
+	if len(reservations) == 0 {
+		common.RespondErrorWithDetails(c, http.StatusNotFound, common.CodeNotFound, "No reservations found for order", gin.H{"order_id": orderId})
+		return
+	}
+
+	now := time.Now()
+	views := make([]reservationView, 0, len(reservations))
+	for _, reservation := range reservations {
+		view := reservationView{ReservationRecord: reservation}
+		// Only active holds count down; everything else reports zero
+		if reservation.Status == "RESERVED" && reservation.ExpiresAt.After(now) {
+			view.RemainingSeconds = int64(reservation.ExpiresAt.Sub(now).Seconds())
+		}
+		view.InGrace = reservation.Status == "RESERVED" && !reservation.ExpiresAt.After(now) && !reservationLapsed(reservation, now)
+		views = append(views, view)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"order_id":             orderId,
+		"reservations":         views,
+		"count":                len(views),
+		"expiry_grace_seconds": int64(reservationGrace().Seconds()),
+	})
+}
+
+// lowStockProduct groups the low-stock warehouse rows of one product
+type lowStockProduct struct {
+	ProductId      int     `json:"product_id"`
+	TotalAvailable int     `json:"total_available"`
+	Warehouses     []gin.H `json:"warehouses"`
+}
+
+// GetLowStock lists products whose available stock has fallen below a threshold, most urgent first.
+// Without ?threshold= each row's own reorder point is used.
+func GetLowStock(c *gin.Context) {
+	db := database.GetDB()
+	query := db.Model(&models.InventoryModel{})
+
+	if t := c.Query("threshold"); t != "" {
+		threshold, err := strconv.Atoi(t)
+		if err != nil || threshold < 0 {
+			common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "threshold must be a non-negative integer")
+			return
+		}
+		query = query.Where("(on_hand - reserved) < ?", threshold)
+	} else {
+		query = query.Where("reorder_point > 0 AND (on_hand - reserved) < reorder_point")
+	}
+
+	var inventoryItems []models.InventoryModel
+	if err := query.Order("(on_hand - reserved) ASC, product_id, ware_house").Find(&inventoryItems).Error; err != nil {
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Database error")
+		return
+	}
+
+	// Group by product, keeping per-warehouse detail
+	products := make([]*lowStockProduct, 0)
+	byProduct := make(map[int]*lowStockProduct)
+	for _, item := range inventoryItems {
+		product, ok := byProduct[item.ProductId]
+		if !ok {
+			product = &lowStockProduct{ProductId: item.ProductId, Warehouses: make([]gin.H, 0)}
+			byProduct[item.ProductId] = product
+			products = append(products, product)
+		}
+
+		available := item.OnHand - item.Reserved
+		product.TotalAvailable += available
+		product.Warehouses = append(product.Warehouses, gin.H{
+			"warehouse":     item.WareHouse,
+			"on_hand":       item.OnHand,
+			"reserved":      item.Reserved,
+			"available":     available,
+			"reorder_point": item.ReorderPoint,
+		})
+	}
+
+	sort.SliceStable(products, func(i, j int) bool {
+		return products[i].TotalAvailable < products[j].TotalAvailable
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"products": products,
+		"count":    len(products),
+	})
+}
+
+// CheckAvailability checks product availability across warehouses and reports, as available_soon, how much
+// reserved stock is held by reservations expiring within reservation.availablesoonwindow
 func CheckAvailability(c *gin.Context) {
 	productIdStr := c.Param("productId")
 	productId, err := strconv.Atoi(productIdStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid product ID")
 		return
 	}
 
-	db := database.GetDB()
+	db, cancel := database.RequestDB(c)
+	defer cancel()
 
 	var inventoryItems []models.InventoryModel
 	if err := db.Where("product_id = ?", productId).Find(&inventoryItems).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		if database.RespondIfTimedOut(c, err) {
+			return
+		}
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Database error")
+		return
+	}
+
+	// Held stock whose reservation expires within the window is likely to come back soon
+	window := availableSoonWindow()
+	var availableSoon int64
+	if err := db.Model(&models.ReservationRecord{}).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("product_id = ? AND status = ? AND expires_at <= ?", productId, "RESERVED", time.Now().Add(window)).
+		Scan(&availableSoon).Error; err != nil {
+		if database.RespondIfTimedOut(c, err) {
+			return
+		}
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Database error")
+		return
+	}
+
+	availability := summarizeAvailability(productId, inventoryItems)
+	availability["available_soon"] = availableSoon
+	availability["available_soon_window_minutes"] = window.Minutes()
+	c.JSON(http.StatusOK, availability)
+}
+
+// availableSoonWindow returns how far ahead to count reserved stock that is about to expire
+func availableSoonWindow() time.Duration {
+	if config := common.GetConfig(); config != nil && config.Reservation.AvailableSoonWindow > 0 {
+		return config.Reservation.AvailableSoonWindow
+	}
+	return 10 * time.Minute
+}
+
+// CheckAvailabilityBulk returns availability for several products, keyed by product ID, in one query
+func CheckAvailabilityBulk(c *gin.Context) {
+	var req models.BulkAvailabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid request", err.Error())
+		return
+	}
+
+	db, cancel := database.RequestDB(c)
+	defer cancel()
+
+	var inventoryItems []models.InventoryModel
+	if err := db.Where("product_id IN ?", req.ProductIds).Find(&inventoryItems).Error; err != nil {
+		if database.RespondIfTimedOut(c, err) {
+			return
+		}
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Database error")
 		return
 	}
 
+	itemsByProduct := make(map[int][]models.InventoryModel, len(req.ProductIds))
+	for _, item := range inventoryItems {
+		itemsByProduct[item.ProductId] = append(itemsByProduct[item.ProductId], item)
+	}
+
+	// Every requested product gets an entry, even with no inventory rows
+	availability := make(map[string]gin.H, len(req.ProductIds))
+	for _, productId := range req.ProductIds {
+		availability[strconv.Itoa(productId)] = summarizeAvailability(productId, itemsByProduct[productId])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"availability": availability,
+		"count":        len(availability),
+	})
+}
+
+// summarizeAvailability totals a product's inventory rows and lists them per warehouse.
+// tracked is false when the product has no inventory rows, so an unknown product can be told
+// apart from a known one that is out of stock.
+func summarizeAvailability(productId int, inventoryItems []models.InventoryModel) gin.H {
 	totalAvailable := 0
 	totalOnHand := 0
 	totalReserved := 0
@@ -532,11 +1288,12 @@ func CheckAvailability(c *gin.Context) {
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	return gin.H{
 		"product_id":      productId,
+		"tracked":         len(inventoryItems) > 0,
 		"total_available": totalAvailable,
 		"total_on_hand":   totalOnHand,
 		"total_reserved":  totalReserved,
 		"warehouses":      warehouses,
-	})
+	}
 }
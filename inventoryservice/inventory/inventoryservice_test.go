@@ -0,0 +1,268 @@
+package inventory
+
+import (
+	"errors"
+	database "inventoryservice/database"
+	dbtest "inventoryservice/database/dbtest"
+	models "inventoryservice/models"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// testModels are the tables the inventory handlers touch
+var testModels = []interface{}{&models.InventoryModel{}, &models.ReservationRecord{}, &models.InventoryReceipt{}, &models.Warehouse{}, &database.OutboxEvent{}}
+
+// createInventory stores an inventory row for a test and returns it with its generated ID
+func createInventory(t *testing.T, db *gorm.DB, inventory models.InventoryModel) models.InventoryModel {
+	t.Helper()
+	if err := db.Create(&inventory).Error; err != nil {
+		t.Fatalf("create inventory: %v", err)
+	}
+	return inventory
+}
+
+// loadInventory reads an inventory row back from the test database
+func loadInventory(t *testing.T, db *gorm.DB, inventoryId int) models.InventoryModel {
+	t.Helper()
+	var inventory models.InventoryModel
+	if err := db.First(&inventory, "inventory_id = ?", inventoryId).Error; err != nil {
+		t.Fatalf("load inventory %d: %v", inventoryId, err)
+	}
+	return inventory
+}
+
+// sendJSON serves one JSON request through the router and returns the recorded response
+func sendJSON(router *gin.Engine, method string, path string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestReserveInventoryNeverOversells(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := dbtest.Open(t, testModels...)
+	inventory := createInventory(t, db, models.InventoryModel{ProductId: 1, WareHouse: "WH1", OnHand: 5})
+
+	router := gin.New()
+	router.POST("/v1/inventory/reserve", ReserveInventory)
+
+	const requests = 8
+	reserved, rejected := 0, 0
+	for i := range requests {
+		n := strconv.Itoa(i)
+		w := sendJSON(router, http.MethodPost, "/v1/inventory/reserve",
+			`{"product_id":1,"quantity":1,"order_id":"ORD-`+n+`","idempotency_key":"key-`+n+`"}`)
+		switch w.Code {
+		case http.StatusOK:
+			reserved++
+		case http.StatusConflict:
+			rejected++
+		default:
+			t.Fatalf("request %d: unexpected status %d: %s", i, w.Code, w.Body.String())
+		}
+	}
+	if reserved != 5 || rejected != requests-5 {
+		t.Fatalf("got %d reserved and %d rejected, want 5 and %d", reserved, rejected, requests-5)
+	}
+
+	got := loadInventory(t, db, inventory.InventoryId)
+	if got.Reserved != 5 || got.Reserved > got.OnHand {
+		t.Fatalf("got on_hand %d reserved %d, want reserved 5 and never above on_hand", got.OnHand, got.Reserved)
+	}
+
+	var records int64
+	db.Model(&models.ReservationRecord{}).Where("status = ?", "RESERVED").Count(&records)
+	if records != 5 {
+		t.Fatalf("got %d reservation records, want 5", records)
+	}
+}
+
+// SQLite has no row locks, so this checks the SQL that Postgres would receive rather than racing handlers
+func TestReserveLocksCandidateRows(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("open dry-run database: %v", err)
+	}
+	var queries []string
+	db.Callback().Query().After("gorm:query").Register("test:record_sql", func(tx *gorm.DB) {
+		queries = append(queries, tx.Statement.SQL.String())
+	})
+
+	tests := []struct {
+		name    string
+		reserve func(tx *gorm.DB) error
+	}{
+		{"single warehouse", func(tx *gorm.DB) error {
+			_, err := reserveItem(tx, 1, 1, "", requestRouting(""), "ORD-1", "key-1", "")
+			return err
+		}},
+		{"split", func(tx *gorm.DB) error {
+			_, err := splitReservation(tx, 1, 1, requestRouting(""), "ORD-1", "key-1", "")
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries = nil
+			// A dry run finds no rows, so the reservation stops at the locking query
+			if err := tt.reserve(db); !errors.Is(err, errInsufficientInventory) {
+				t.Fatalf("got %v, want %v", err, errInsufficientInventory)
+			}
+			if len(queries) != 1 || !strings.HasSuffix(queries[0], "FOR UPDATE") {
+				t.Fatalf("got queries %q, want one ending in FOR UPDATE", queries)
+			}
+		})
+	}
+}
+
+func TestReleaseAndShipClampStaleReservations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		path       string
+		handler    gin.HandlerFunc
+		wantOnHand int
+		wantStatus string
+	}{
+		{"release", "/v1/inventory/release", ReleaseInventory, 10, "RELEASED"},
+		{"ship", "/v1/inventory/ship", ShipInventory, 7, "SHIPPED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.Open(t, testModels...)
+			router := gin.New()
+			router.POST(tt.path, tt.handler)
+
+			// The reservation holds 3 units but the stock it held was already given back, leaving only 1 reserved
+			inventory := createInventory(t, db, models.InventoryModel{ProductId: 1, WareHouse: "WH1", OnHand: 10, Reserved: 1})
+			now := time.Now()
+			reservation := models.ReservationRecord{ProductId: 1, Warehouse: "WH1", Quantity: 3, OrderId: "ORD-1", IdempotencyKey: "stale",
+				Status: "RESERVED", ReservedAt: now, ExpiresAt: now.Add(time.Hour)}
+			if err := db.Create(&reservation).Error; err != nil {
+				t.Fatalf("create reservation: %v", err)
+			}
+
+			w := sendJSON(router, http.MethodPost, tt.path, `{"idempotency_key":"stale","order_id":"ORD-1"}`)
+			if w.Code != http.StatusOK {
+				t.Fatalf("%s: got %d, want %d: %s", tt.name, w.Code, http.StatusOK, w.Body.String())
+			}
+
+			got := loadInventory(t, db, inventory.InventoryId)
+			if got.Reserved != 0 || got.OnHand != tt.wantOnHand {
+				t.Fatalf("got on_hand %d reserved %d, want on_hand %d reserved 0", got.OnHand, got.Reserved, tt.wantOnHand)
+			}
+
+			var stored models.ReservationRecord
+			if err := db.First(&stored, "id = ?", reservation.ID).Error; err != nil {
+				t.Fatalf("load reservation: %v", err)
+			}
+			if stored.Status != tt.wantStatus {
+				t.Fatalf("reservation is %s, want %s", stored.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestReserveInventoryIdempotentReplay(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := dbtest.Open(t, testModels...)
+	inventory := createInventory(t, db, models.InventoryModel{ProductId: 1, WareHouse: "WH1", OnHand: 10})
+	createInventory(t, db, models.InventoryModel{ProductId: 2, WareHouse: "WH1", OnHand: 10})
+
+	router := gin.New()
+	router.POST("/v1/inventory/reserve", ReserveInventory)
+
+	w := sendJSON(router, http.MethodPost, "/v1/inventory/reserve", `{"product_id":1,"quantity":2,"order_id":"ORD-1","idempotency_key":"replay"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("first reserve: got %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		status   int
+		wantBody string
+	}{
+		{"matching replay", `{"product_id":1,"quantity":2,"order_id":"ORD-1","idempotency_key":"replay"}`, http.StatusOK, `"idempotent":true`},
+		{"matching replay naming the warehouse", `{"product_id":1,"quantity":2,"order_id":"ORD-1","warehouse":"WH1","idempotency_key":"replay"}`, http.StatusOK, `"idempotent":true`},
+		{"different quantity", `{"product_id":1,"quantity":3,"order_id":"ORD-1","idempotency_key":"replay"}`, http.StatusConflict, "IDEMPOTENCY_KEY_REUSE"},
+		{"different product", `{"product_id":2,"quantity":2,"order_id":"ORD-1","idempotency_key":"replay"}`, http.StatusConflict, "IDEMPOTENCY_KEY_REUSE"},
+		{"different order", `{"product_id":1,"quantity":2,"order_id":"ORD-2","idempotency_key":"replay"}`, http.StatusConflict, "IDEMPOTENCY_KEY_REUSE"},
+		{"different warehouse", `{"product_id":1,"quantity":2,"order_id":"ORD-1","warehouse":"WH2","idempotency_key":"replay"}`, http.StatusConflict, "IDEMPOTENCY_KEY_REUSE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := sendJSON(router, http.MethodPost, "/v1/inventory/reserve", tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("got %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("body does not contain %s: %s", tt.wantBody, w.Body.String())
+			}
+		})
+	}
+
+	// Replays never reserve again, whether they match or not
+	if got := loadInventory(t, db, inventory.InventoryId); got.Reserved != 2 {
+		t.Fatalf("reserved is %d after replays, want 2", got.Reserved)
+	}
+	var records int64
+	db.Model(&models.ReservationRecord{}).Where("idempotency_key = ?", "replay").Count(&records)
+	if records != 1 {
+		t.Fatalf("got %d reservation records for the key, want 1", records)
+	}
+}
+
+func TestReserveInventoryBatchIdempotencyKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		header  string
+		bodyKey string
+		status  int
+		wantKey string
+	}{
+		{"body key", "", `,"idempotency_key":"body-key"`, http.StatusOK, "body-key"},
+		{"header key", "header-key", "", http.StatusOK, "header-key"},
+		{"body key wins over header", "header-key", `,"idempotency_key":"body-key"`, http.StatusOK, "body-key"},
+		{"no key", "", "", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.Open(t, testModels...)
+			createInventory(t, db, models.InventoryModel{ProductId: 1, WareHouse: "WH1", OnHand: 10})
+			router := gin.New()
+			router.POST("/v1/inventory/reserve/batch", ReserveInventoryBatch)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/inventory/reserve/batch",
+				strings.NewReader(`{"items":[{"product_id":1,"quantity":2}],"order_id":"ORD-1"`+tt.bodyKey+`}`))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.header != "" {
+				req.Header.Set("Idempotency-Key", tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("got %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+
+			var records int64
+			db.Model(&models.ReservationRecord{}).Where("idempotency_key = ?", tt.wantKey).Count(&records)
+			if tt.wantKey != "" && records != 1 {
+				t.Fatalf("got %d reservation records under %q, want 1", records, tt.wantKey)
+			}
+		})
+	}
+}
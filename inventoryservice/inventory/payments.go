@@ -0,0 +1,70 @@
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	common "inventoryservice/common"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// paymentClient is used to confirm an order was paid before it ships
+var paymentClient = &http.Client{Timeout: 5 * time.Second}
+
+// orderPayments is the part of the payment service's order lookup the ship check reads
+type orderPayments struct {
+	Payments []struct {
+		Status string `json:"status"`
+	} `json:"payments"`
+}
+
+// paymentRequired reports whether shipping must wait for a completed payment, and where to check
+func paymentRequired() (bool, string) {
+	config := common.GetConfig()
+	if config == nil || !config.Payment.RequirePayment {
+		return false, ""
+	}
+	return true, strings.TrimRight(config.Payment.Url, "/")
+}
+
+// orderPaid asks the payment service whether the order has a COMPLETED payment.
+// An order the payment service has never seen is unpaid rather than an error.
+func orderPaid(baseUrl string, orderId string, requestId string) (bool, error) {
+	if baseUrl == "" {
+		return false, fmt.Errorf("payment service URL is not configured")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, baseUrl+"/v1/payments/order/"+url.PathEscape(orderId), nil)
+	if err != nil {
+		return false, err
+	}
+	if requestId != "" {
+		req.Header.Set(common.RequestIdHeader, requestId)
+	}
+
+	resp, err := paymentClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("payment service returned status %d", resp.StatusCode)
+	}
+
+	var body orderPayments
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return false, err
+	}
+	for _, payment := range body.Payments {
+		if payment.Status == "COMPLETED" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,94 @@
+package inventory
+
+import (
+	"errors"
+	common "inventoryservice/common"
+	database "inventoryservice/database"
+	models "inventoryservice/models"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReceiveInventory adds received stock to on_hand, creating the product/warehouse row if needed,
+// and records the receipt
+func ReceiveInventory(c *gin.Context) {
+	var req models.ReceiveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid request", err.Error())
+		return
+	}
+
+	req.Warehouse = strings.TrimSpace(req.Warehouse)
+	if req.Warehouse == "" {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "warehouse is required")
+		return
+	}
+	if err := validateWarehouse(database.GetDB(), req.Warehouse); err != nil {
+		respondWarehouseError(c, req.Warehouse, err)
+		return
+	}
+
+	var inventory models.InventoryModel
+	var receipt models.InventoryReceipt
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		// Serialize receipts per product so two first receipts can't both create the warehouse row
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", req.ProductId).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("product_id = ? AND ware_house = ?", req.ProductId, req.Warehouse).
+			First(&inventory).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			inventory = models.InventoryModel{
+				ProductId: req.ProductId,
+				WareHouse: req.Warehouse,
+				OnHand:    req.Quantity,
+			}
+			if err := tx.Create(&inventory).Error; err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			inventory.OnHand += req.Quantity
+			if err := saveInventory(tx, &inventory); err != nil {
+				return err
+			}
+		}
+
+		receipt = models.InventoryReceipt{
+			InventoryId: inventory.InventoryId,
+			ProductId:   req.ProductId,
+			Warehouse:   req.Warehouse,
+			Quantity:    req.Quantity,
+			Reference:   req.Reference,
+			ReceivedAt:  now,
+		}
+		return tx.Create(&receipt).Error
+	})
+	if errors.Is(err, errInventoryConflict) {
+		common.RespondError(c, http.StatusConflict, common.CodeConflict, "Inventory was modified concurrently, please retry")
+		return
+	}
+	if err != nil {
+		log.Errorf("Failed to receive inventory for product %d in %s: %v", req.ProductId, req.Warehouse, err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to receive inventory")
+		return
+	}
+
+	log.Infof("Received %d units of product %d into %s (ref %q)", req.Quantity, req.ProductId, req.Warehouse, req.Reference)
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Inventory received successfully",
+		"inventory": inventory,
+		"receipt":   receipt,
+	})
+}
@@ -0,0 +1,60 @@
+package inventory
+
+import (
+	common "inventoryservice/common"
+	database "inventoryservice/database"
+	models "inventoryservice/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// reservationStatuses lists the statuses a reservation can be filtered by
+var reservationStatuses = map[string]bool{
+	"RESERVED": true, "CONFIRMED": true, "SHIPPED": true, "RELEASED": true, "EXPIRED": true,
+}
+
+// ListReservations returns reservation rows, newest first, optionally filtered by status, product and creator
+func ListReservations(c *gin.Context) {
+	limit, offset := common.Paginate(c)
+
+	query := database.GetDB().Model(&models.ReservationRecord{})
+	if status := strings.ToUpper(strings.TrimSpace(c.Query("status"))); status != "" {
+		if !reservationStatuses[status] {
+			common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "status must be one of RESERVED, CONFIRMED, SHIPPED, RELEASED, EXPIRED")
+			return
+		}
+		query = query.Where("status = ?", status)
+	}
+	if p := c.Query("product_id"); p != "" {
+		productId, err := strconv.Atoi(p)
+		if err != nil {
+			common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "product_id must be an integer")
+			return
+		}
+		query = query.Where("product_id = ?", productId)
+	}
+	if createdBy := strings.TrimSpace(c.Query("created_by")); createdBy != "" {
+		query = query.Where("created_by = ?", createdBy)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		log.Errorf("DB count error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to list reservations")
+		return
+	}
+
+	var reservations []models.ReservationRecord
+	if err := query.Order("reserved_at desc, id desc").Limit(limit).Offset(offset).Find(&reservations).Error; err != nil {
+		log.Errorf("DB query error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to list reservations")
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, common.NewPageResponse(reservations, total, limit, offset).WithLegacyKey("reservations", len(reservations)))
+}
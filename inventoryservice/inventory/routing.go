@@ -0,0 +1,92 @@
+package inventory
+
+import (
+	common "inventoryservice/common"
+	models "inventoryservice/models"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Warehouse routing policies for reservations that don't name a warehouse
+const (
+	// RoutingMostStock draws from the warehouse with the most available stock
+	RoutingMostStock = "most_stock"
+	// RoutingNearest prefers warehouses in the request's region, then falls back to most stock
+	RoutingNearest = "nearest"
+	// RoutingFewestWarehouses keeps a batch in a single warehouse when one can cover every item
+	RoutingFewestWarehouses = "fewest_warehouses"
+)
+
+// routing decides which warehouse a reservation draws from
+type routing struct {
+	Policy string
+	Region string
+}
+
+// requestRouting applies the configured policy to a request; nearest needs a region, so
+// without one the request is routed by most stock instead
+func requestRouting(region string) routing {
+	policy := configuredRoutingPolicy()
+	region = strings.TrimSpace(region)
+	if policy == RoutingNearest && region == "" {
+		policy = RoutingMostStock
+	}
+	return routing{Policy: policy, Region: region}
+}
+
+// configuredRoutingPolicy returns reservation.routingpolicy, defaulting to most_stock
+func configuredRoutingPolicy() string {
+	config := common.GetConfig()
+	if config == nil || config.Reservation.RoutingPolicy == "" {
+		return RoutingMostStock
+	}
+
+	policy := strings.ToLower(strings.TrimSpace(config.Reservation.RoutingPolicy))
+	switch policy {
+	case RoutingMostStock, RoutingNearest, RoutingFewestWarehouses:
+		return policy
+	}
+	log.Warnf("Unknown reservation routing policy %q, using %s", config.Reservation.RoutingPolicy, RoutingMostStock)
+	return RoutingMostStock
+}
+
+// order sorts candidate inventory rows so the preferred warehouse comes first
+func (r routing) order(query *gorm.DB) *gorm.DB {
+	if r.Policy == RoutingNearest {
+		return query.Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "CASE WHEN ware_house IN (SELECT code FROM warehouses WHERE region = ?) THEN 0 ELSE 1 END, (on_hand - reserved) DESC, ware_house",
+			Vars:               []interface{}{r.Region},
+			WithoutParentheses: true,
+		}})
+	}
+	return query.Order("(on_hand - reserved) DESC, ware_house")
+}
+
+// warehouseCoveringAll finds a warehouse with enough available stock for every item, or "" if none can.
+// The rows aren't locked here; reserveItem locks and re-checks them when the batch is reserved.
+func warehouseCoveringAll(tx *gorm.DB, items []models.BatchReservationItem) (string, error) {
+	conditions := tx.Where("1 = 0")
+	products := make(map[int]bool, len(items))
+	for _, item := range items {
+		conditions = conditions.Or("product_id = ? AND (on_hand - reserved) >= ?", item.ProductId, item.Quantity)
+		products[item.ProductId] = true
+	}
+
+	var warehouses []string
+	if err := tx.Model(&models.InventoryModel{}).
+		Where(conditions).
+		Group("ware_house").
+		Having("COUNT(DISTINCT product_id) = ?", len(products)).
+		Order("ware_house").
+		Limit(1).
+		Pluck("ware_house", &warehouses).Error; err != nil {
+		return "", err
+	}
+	if len(warehouses) == 0 {
+		return "", nil
+	}
+	return warehouses[0], nil
+}
@@ -0,0 +1,64 @@
+package inventory
+
+import (
+	common "inventoryservice/common"
+	database "inventoryservice/database"
+	models "inventoryservice/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// inventorySummary is the stock-wide snapshot returned by GetInventorySummary
+type inventorySummary struct {
+	TotalSkus      int64 `json:"total_skus"`
+	TotalOnHand    int64 `json:"total_on_hand"`
+	TotalReserved  int64 `json:"total_reserved"`
+	TotalAvailable int64 `json:"total_available"`
+	LowStockItems  int64 `json:"low_stock_items"`
+	Warehouses     int64 `json:"warehouses"`
+}
+
+// GetInventorySummary returns SKU, stock and warehouse totals across all inventory in one aggregate query.
+// Low-stock rows are counted like GetLowStock: below ?threshold= if given, otherwise below their reorder point.
+func GetInventorySummary(c *gin.Context) {
+	lowStock, rule := "reorder_point > 0 AND (on_hand - reserved) < reorder_point", "reorder_point"
+	var args []interface{}
+	if t := c.Query("threshold"); t != "" {
+		threshold, err := strconv.Atoi(t)
+		if err != nil || threshold < 0 {
+			common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "threshold must be a non-negative integer")
+			return
+		}
+		lowStock, rule = "(on_hand - reserved) < ?", "threshold"
+		args = append(args, threshold)
+	}
+
+	db, cancel := database.RequestDB(c)
+	defer cancel()
+
+	var summary inventorySummary
+	err := db.Model(&models.InventoryModel{}).
+		Select(`COUNT(DISTINCT product_id) AS total_skus,
+			COALESCE(SUM(on_hand), 0) AS total_on_hand,
+			COALESCE(SUM(reserved), 0) AS total_reserved,
+			COALESCE(SUM(on_hand - reserved), 0) AS total_available,
+			COALESCE(SUM(CASE WHEN `+lowStock+` THEN 1 ELSE 0 END), 0) AS low_stock_items,
+			COUNT(DISTINCT ware_house) AS warehouses`, args...).
+		Scan(&summary).Error
+	if err != nil {
+		if database.RespondIfTimedOut(c, err) {
+			return
+		}
+		log.Errorf("DB summary query error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to fetch inventory summary")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"inventory_summary": summary,
+		"low_stock_rule":    rule,
+	})
+}
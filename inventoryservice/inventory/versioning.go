@@ -0,0 +1,30 @@
+package inventory
+
+import (
+	"errors"
+	models "inventoryservice/models"
+
+	"gorm.io/gorm"
+)
+
+// errInventoryConflict is returned when an inventory row changed between being read and written
+var errInventoryConflict = errors.New("inventory was modified concurrently")
+
+// saveInventory writes every column of an inventory row only if its version is still the one that
+// was read, and bumps the version. Zero rows affected means another writer got there first.
+func saveInventory(tx *gorm.DB, inventory *models.InventoryModel) error {
+	expected := inventory.Version
+	inventory.Version = expected + 1
+
+	result := tx.Model(inventory).
+		Where("version = ?", expected).
+		Select("product_id", "ware_house", "on_hand", "reserved", "reorder_point", "version", "updated_at").
+		Updates(inventory)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = errInventoryConflict
+	}
+	if result.Error != nil {
+		inventory.Version = expected
+	}
+	return result.Error
+}
@@ -0,0 +1,241 @@
+package inventory
+
+import (
+	"encoding/csv"
+	"errors"
+	common "inventoryservice/common"
+	database "inventoryservice/database"
+	models "inventoryservice/models"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errUnknownWarehouse is returned when a warehouse code is not in the master list or is inactive
+var errUnknownWarehouse = errors.New("unknown or inactive warehouse")
+
+// GetWarehouses lists the warehouse master list; ?active=true limits it to active warehouses
+func GetWarehouses(c *gin.Context) {
+	query := database.GetDB().Model(&models.Warehouse{})
+	if c.Query("active") == "true" {
+		query = query.Where("active = ?", true)
+	}
+
+	var warehouses []models.Warehouse
+	if err := query.Order("code asc").Find(&warehouses).Error; err != nil {
+		log.Errorf("DB query error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to list warehouses")
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"warehouses": warehouses,
+		"count":      len(warehouses),
+	})
+}
+
+// warehouseStockRow is one product's stock in a warehouse
+type warehouseStockRow struct {
+	ProductId    int `json:"product_id"`
+	OnHand       int `json:"on_hand"`
+	Reserved     int `json:"reserved"`
+	Available    int `json:"available"`
+	ReorderPoint int `json:"reorder_point"`
+}
+
+// warehouseTotals sums stock across every row in a warehouse, not just the returned page
+type warehouseTotals struct {
+	OnHand    int64 `json:"on_hand"`
+	Reserved  int64 `json:"reserved"`
+	Available int64 `json:"available"`
+}
+
+// warehouseStockResponse is a page of a warehouse's stock plus warehouse-wide totals
+type warehouseStockResponse struct {
+	common.PageResponse
+	Warehouse models.Warehouse `json:"warehouse"`
+	Totals    warehouseTotals  `json:"totals"`
+}
+
+// GetWarehouseStock lists every inventory row in one warehouse, sorted by available quantity
+// (?order=asc|desc, default asc), with on_hand, reserved and available totals for the warehouse
+func GetWarehouseStock(c *gin.Context) {
+	code := c.Param("warehouse")
+
+	sortOrder := "asc"
+	if order := strings.ToLower(c.DefaultQuery("order", "asc")); order == "desc" {
+		sortOrder = "desc"
+	} else if order != "asc" {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "order must be asc or desc")
+		return
+	}
+
+	db := database.GetDB()
+
+	var warehouse models.Warehouse
+	if err := db.First(&warehouse, "code = ?", code).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.RespondErrorWithDetails(c, http.StatusNotFound, common.CodeNotFound, "Warehouse not found", gin.H{"warehouse": code})
+			return
+		}
+		log.Errorf("DB query error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to fetch warehouse stock")
+		return
+	}
+
+	query := db.Model(&models.InventoryModel{}).Where("ware_house = ?", code)
+
+	var totals warehouseTotals
+	if err := query.Session(&gorm.Session{}).
+		Select("COALESCE(SUM(on_hand), 0) AS on_hand, COALESCE(SUM(reserved), 0) AS reserved, COALESCE(SUM(on_hand - reserved), 0) AS available").
+		Scan(&totals).Error; err != nil {
+		log.Errorf("DB totals error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to fetch warehouse stock")
+		return
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		log.Errorf("DB count error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to fetch warehouse stock")
+		return
+	}
+
+	limit, offset := common.Paginate(c)
+
+	rows := make([]warehouseStockRow, 0, limit)
+	if err := query.Select("product_id, on_hand, reserved, on_hand - reserved AS available, reorder_point").
+		Order("available " + sortOrder + ", product_id asc").
+		Limit(limit).Offset(offset).
+		Scan(&rows).Error; err != nil {
+		log.Errorf("DB query error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to fetch warehouse stock")
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, warehouseStockResponse{
+		PageResponse: common.NewPageResponse(rows, total, limit, offset),
+		Warehouse:    warehouse,
+		Totals:       totals,
+	})
+}
+
+// SeedWarehouses loads the warehouse master list from seeddata/eci_warehouses.csv
+func SeedWarehouses(c *gin.Context) {
+	upserted, err := seedWarehouses(database.GetDB())
+	if err != nil {
+		log.Errorf("Warehouse seed failed: %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Warehouse seed failed")
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"upserted": upserted})
+}
+
+// EnsureWarehouses loads the warehouse CSV when the master list is empty, so reservations and receipts
+// naming a warehouse work on a fresh deploy without a manual seed
+func EnsureWarehouses() {
+	db := database.GetDB()
+
+	var count int64
+	if err := db.Model(&models.Warehouse{}).Count(&count).Error; err != nil {
+		log.Errorf("Failed to count warehouses: %v", err)
+		return
+	}
+	if count > 0 {
+		return
+	}
+
+	upserted, err := seedWarehouses(db)
+	if err != nil {
+		log.Errorf("Warehouse master list is empty and seeding it failed: %v", err)
+		return
+	}
+	log.Infof("Seeded %d warehouses into the empty master list", upserted)
+}
+
+// seedWarehouses upserts every row of the warehouse CSV, so re-seeding updates names and active flags
+func seedWarehouses(db *gorm.DB) (int, error) {
+	f, err := os.Open(filepath.Join("seeddata", "eci_warehouses.csv"))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return 0, err
+	}
+	if len(records) < 2 {
+		return 0, errors.New("warehouse CSV contains no data")
+	}
+
+	idx := make(map[string]int)
+	for i, h := range records[0] {
+		idx[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	field := func(row []string, name string) string {
+		if i, ok := idx[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	upserted := 0
+	for ri, row := range records[1:] {
+		code := field(row, "code")
+		if code == "" {
+			log.Warnf("Skipping warehouse CSV row %d: missing code", ri+2)
+			continue
+		}
+
+		warehouse := models.Warehouse{Code: code, Name: field(row, "name"), Region: field(row, "region"), Active: true}
+		if v := field(row, "active"); v != "" {
+			if active, perr := strconv.ParseBool(v); perr == nil {
+				warehouse.Active = active
+			}
+		}
+
+		if err := db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "code"}},
+			DoUpdates: clause.AssignmentColumns([]string{"name", "region", "active", "updated_at"}),
+		}).Create(&warehouse).Error; err != nil {
+			log.Errorf("DB upsert error at warehouse CSV row %d: %v", ri+2, err)
+			continue
+		}
+		upserted++
+	}
+
+	return upserted, nil
+}
+
+// validateWarehouse checks that a warehouse code is in the master list and active
+func validateWarehouse(db *gorm.DB, code string) error {
+	var count int64
+	if err := db.Model(&models.Warehouse{}).Where("code = ? AND active = ?", code, true).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errUnknownWarehouse
+	}
+	return nil
+}
+
+// respondWarehouseError reports a failed warehouse check: 400 for an unknown code, 500 otherwise
+func respondWarehouseError(c *gin.Context, code string, err error) {
+	if errors.Is(err, errUnknownWarehouse) {
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Unknown or inactive warehouse", gin.H{"warehouse": code})
+		return
+	}
+	log.Errorf("Warehouse lookup failed for %s: %v", code, err)
+	common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to validate warehouse")
+}
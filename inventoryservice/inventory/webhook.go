@@ -0,0 +1,78 @@
+package inventory
+
+import (
+	common "inventoryservice/common"
+	database "inventoryservice/database"
+	models "inventoryservice/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Reservation lifecycle events sent to the webhook
+const (
+	EventReservationExpired  = "reservation.expired"
+	EventReservationReleased = "reservation.released"
+	EventReservationShipped  = "reservation.shipped"
+)
+
+// defaultWebhookRetries is used when webhook.retries is not set
+const defaultWebhookRetries = 2
+
+// reservationEvent is the webhook body describing a reservation that stopped holding stock
+type reservationEvent struct {
+	Event      string     `json:"event"`
+	OrderId    string     `json:"order_id"`
+	ProductId  int        `json:"product_id"`
+	Quantity   int        `json:"quantity"`
+	Warehouse  string     `json:"warehouse"`
+	ExpiredAt  *time.Time `json:"expired_at,omitempty"`
+	OccurredAt time.Time  `json:"occurred_at"`
+}
+
+// newReservationEvent builds the webhook body for a reservation that reached the given event
+func newReservationEvent(event string, reservation models.ReservationRecord) reservationEvent {
+	e := reservationEvent{
+		Event:      event,
+		OrderId:    reservation.OrderId,
+		ProductId:  reservation.ProductId,
+		Quantity:   reservation.Quantity,
+		Warehouse:  reservation.Warehouse,
+		OccurredAt: reservation.UpdatedAt,
+	}
+	if event == EventReservationExpired {
+		e.ExpiredAt = &reservation.UpdatedAt
+	}
+	return e
+}
+
+// enqueueReservationEvents records one webhook event per reservation in tx, so the events are saved only if
+// the change commits and the outbox publisher delivers them afterwards. It is a no-op when no webhook URL
+// is configured. Each event gets webhook.retries retries on top of its first attempt.
+func enqueueReservationEvents(tx *gorm.DB, event string, reservations []models.ReservationRecord, requestId string) error {
+	url, retries := webhookSettings()
+	if url == "" {
+		return nil
+	}
+
+	for _, reservation := range reservations {
+		if err := database.EnqueueEvent(tx, event, url, newReservationEvent(event, reservation), requestId, retries+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// webhookSettings returns the configured webhook URL and retry count
+func webhookSettings() (string, int) {
+	config := common.GetConfig()
+	if config == nil {
+		return "", 0
+	}
+
+	retries := defaultWebhookRetries
+	if config.Webhook.Retries > 0 {
+		retries = config.Webhook.Retries
+	}
+	return config.Webhook.Url, retries
+}
@@ -1,15 +1,31 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	auth "inventoryservice/auth"
 	common "inventoryservice/common"
 	database "inventoryservice/database"
 	inventory "inventoryservice/inventory"
+	models "inventoryservice/models"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	log "github.com/sirupsen/logrus"
 )
 
 func main() {
+	// Admin-only routes verify customer-service tokens, so the shared secret is required
+	if err := auth.LoadSecret(); err != nil {
+		log.Errorf("JWT secret setup failed: %v", err)
+		return
+	}
+
 	err := common.ConfigSetup("configuration/dbconfig.yaml")
 	if err != nil {
 		log.Error("ConfigSetup failed")
@@ -26,37 +42,102 @@ func main() {
 		log.Info("DB Setup Success")
 	}
 
+	// Cancelled on SIGINT/SIGTERM so background jobs and the server can wind down
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Reserve and receive validate against the warehouse master list, so a fresh database needs one
+	inventory.EnsureWarehouses()
+
 	// Start reservation cleanup job
-	inventory.StartCleanupJob()
+	inventory.StartCleanupJob(ctx)
+
+	// Deliver reservation events recorded in the outbox
+	database.StartOutboxPublisher(ctx, "inventory")
+
+	// JSON bodies with unknown fields are rejected, so a misspelled field is a 400 rather than silently ignored
+	binding.EnableDecoderDisallowUnknownFields = true
+
+	router := setupRouter(configuration)
+
+	//:: Note: For local testing use below
+	//router.Run("localhost:3000")
+
+	//:: For Docker use below
+	server := &http.Server{
+		Addr:    ":3000",
+		Handler: router,
+	}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("HTTP server failed: %v", err)
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+	log.Info("Shutdown signal received, draining HTTP server")
+
+	// Give in-flight requests time to finish their transactions
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Errorf("HTTP server shutdown failed: %v", err)
+	} else {
+		log.Info("HTTP server stopped")
+	}
 
-	router := gin.Default()
+	log.Info("Inventory service shut down")
+}
+
+// setupRouter builds the router with the service's middleware and every route
+func setupRouter(configuration *common.Configuration) *gin.Engine {
+	// RequestLogger replaces gin's default access log with one structured line per request
+	router := gin.New()
+	router.Use(gin.Recovery(), common.RequestLogger())
+	router.Use(common.CORS(configuration.Cors.AllowedOrigins))
+	router.Use(common.LimitRequestBody(configuration.Request.MaxBodyBytes, configuration.Request.MaxUploadBytes))
 
-	// Add health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "healthy", "service": "inventory"})
-	})
+	// Health checks: /live for liveness, /ready (and /health) for readiness, which pings the database
+	router.GET("/live", database.LivenessCheck("inventory"))
+	router.GET("/ready", database.ReadinessCheck("inventory"))
+	router.GET("/health", database.ReadinessCheck("inventory"))
+
+	// Connection pool stats, for diagnosing pool exhaustion under load
+	router.GET("/debug/dbstats", database.DbStats("inventory"))
 
 	// API versioning with /v1
 	v1 := router.Group("/v1")
 	{
+		v1.POST("/admin/reset", database.ResetTables("inventory", &models.InventoryModel{}, &models.ReservationRecord{}, &models.InventoryReceipt{}, &models.Warehouse{}, &database.OutboxEvent{}))
 		v1.POST("/inventory", inventory.AddInventory)
-		v1.PATCH("/inventory/:id", inventory.UpdateInventory)
-		v1.DELETE("/inventory/:id", inventory.DeleteInventory)
+		v1.PATCH("/inventory/:id", auth.AuthRequired(), auth.RequireRole(auth.RoleAdmin), inventory.UpdateInventory)
+		v1.DELETE("/inventory/:id", auth.AuthRequired(), auth.RequireRole(auth.RoleAdmin), inventory.DeleteInventory)
 		v1.GET("/inventory/:id", inventory.GetInventoryById)
 		v1.GET("/inventory", inventory.GetAllInventory)
-		v1.POST("/inventory/seed", inventory.SeedInventoryDetail)
+		v1.POST("/inventory/seed", database.RequireReset("inventory"), inventory.SeedInventoryDetail)
+		v1.GET("/warehouses", inventory.GetWarehouses)
+		v1.GET("/inventory/warehouses/:warehouse", inventory.GetWarehouseStock)
+		v1.POST("/warehouses/seed", database.RequireReset("inventory"), inventory.SeedWarehouses)
+		v1.POST("/inventory/receive", inventory.ReceiveInventory)
 
 		// New reservation endpoints as per problem statement
 		v1.POST("/inventory/reserve", inventory.ReserveInventory)
+		v1.POST("/inventory/reserve/batch", inventory.ReserveInventoryBatch)
+		v1.POST("/inventory/reserve/extend", inventory.ExtendReservation)
 		v1.POST("/inventory/release", inventory.ReleaseInventory)
+		v1.POST("/inventory/confirm", inventory.ConfirmInventory)
 		v1.POST("/inventory/ship", inventory.ShipInventory)
 		v1.GET("/inventory/availability/:productId", inventory.CheckAvailability)
+		v1.POST("/inventory/availability", inventory.CheckAvailabilityBulk)
+		v1.GET("/inventory/low-stock", inventory.GetLowStock)
+		v1.GET("/inventory/summary", inventory.GetInventorySummary)
+		v1.GET("/inventory/reservations", auth.AuthRequired(), auth.RequireRole(auth.RoleAdmin), inventory.ListReservations)
 		v1.GET("/inventory/reservations/status", inventory.GetReservationStatus)
+		v1.GET("/inventory/reservations/:orderId", inventory.GetReservationsByOrder)
 	}
 
-	//:: Note: For local testing use below
-	//router.Run("localhost:3000")
-
-	//:: For Docker use below
-	router.Run(":3000")
+	return router
 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	auth "inventoryservice/auth"
+	common "inventoryservice/common"
+	database "inventoryservice/database"
+	dbtest "inventoryservice/database/dbtest"
+	models "inventoryservice/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret"
+
+// signToken issues a token the auth middleware accepts, as the customer service would at login
+func signToken(t *testing.T, role string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":  1,
+		"role": role,
+		"exp":  time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return token
+}
+
+func TestInventoryByIdRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("JWT_SECRET", testSecret)
+	if err := auth.LoadSecret(); err != nil {
+		t.Fatalf("load secret: %v", err)
+	}
+	db := dbtest.Open(t, &models.InventoryModel{}, &models.ReservationRecord{}, &database.OutboxEvent{})
+	router := setupRouter(&common.Configuration{})
+
+	inventory := models.InventoryModel{ProductId: 7, WareHouse: "WH1", OnHand: 10}
+	if err := db.Create(&inventory).Error; err != nil {
+		t.Fatalf("create inventory: %v", err)
+	}
+	path := "/v1/inventory/" + strconv.Itoa(inventory.InventoryId)
+	adminToken, customerToken := signToken(t, auth.RoleAdmin), signToken(t, "customer")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	var got models.InventoryModel
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode inventory: %v: %s", err, w.Body.String())
+	}
+	if got.InventoryId != inventory.InventoryId || got.ProductId != 7 {
+		t.Fatalf("got inventory %+v, want id %d for product 7", got, inventory.InventoryId)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+	}{
+		{"get existing", http.MethodGet, path, "", http.StatusOK},
+		{"get unknown", http.MethodGet, "/v1/inventory/999", "", http.StatusNotFound},
+		{"get non-numeric", http.MethodGet, "/v1/inventory/abc", "", http.StatusBadRequest},
+		{"delete without token", http.MethodDelete, path, "", http.StatusUnauthorized},
+		{"delete as customer", http.MethodDelete, path, customerToken, http.StatusForbidden},
+		{"delete non-numeric", http.MethodDelete, "/v1/inventory/abc", adminToken, http.StatusBadRequest},
+		{"delete as admin", http.MethodDelete, path, adminToken, http.StatusOK},
+		{"delete again", http.MethodDelete, path, adminToken, http.StatusNotFound},
+		{"get deleted", http.MethodGet, path, "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("%s %s: got %d, want %d: %s", tt.method, tt.path, w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+}
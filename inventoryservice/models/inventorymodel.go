@@ -3,12 +3,15 @@ package models
 import "time"
 
 type InventoryModel struct {
-	InventoryId int       `json:"inventory_id" gorm:"primaryKey;autoIncrement:true"`
-	ProductId   int       `json:"product_id"`
-	WareHouse   string    `json:"warehouse"`
-	OnHand      int       `json:"onhand"`
-	Reserved    int       `json:"reserved"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	InventoryId  int       `json:"inventory_id" gorm:"primaryKey;autoIncrement:true"`
+	ProductId    int       `json:"product_id"`
+	WareHouse    string    `json:"warehouse"`
+	OnHand       int       `json:"onhand"`
+	Reserved     int       `json:"reserved"`
+	ReorderPoint int       `json:"reorder_point"`
+	Version      int       `json:"version" gorm:"not null;default:1"` // optimistic lock, bumped on every write
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
 // ReservationRequest represents a request to reserve inventory
@@ -16,22 +19,42 @@ type ReservationRequest struct {
 	ProductId      int    `json:"product_id" binding:"required"`
 	Quantity       int    `json:"quantity" binding:"required,min=1"`
 	Warehouse      string `json:"warehouse,omitempty"`
-	IdempotencyKey string `json:"idempotency_key" binding:"required"`
+	IdempotencyKey string `json:"idempotency_key"` // may come from the Idempotency-Key header instead
 	OrderId        string `json:"order_id" binding:"required"`
+	AllowSplit     bool   `json:"allow_split,omitempty"`
+	Region         string `json:"region,omitempty"` // drives the nearest routing policy
+	DryRun         bool   `json:"dry_run,omitempty"`
 }
 
 // ReservationRecord tracks individual reservations with TTL
+// A single idempotency key may cover several rows (one per product and warehouse)
 type ReservationRecord struct {
 	ID             int       `json:"id" gorm:"primaryKey;autoIncrement:true"`
-	ProductId      int       `json:"product_id"`
-	Warehouse      string    `json:"warehouse"`
+	ProductId      int       `json:"product_id" gorm:"uniqueIndex:idx_reservation_item,priority:2"`
+	Warehouse      string    `json:"warehouse" gorm:"uniqueIndex:idx_reservation_item,priority:3"`
 	Quantity       int       `json:"quantity"`
 	OrderId        string    `json:"order_id"`
-	IdempotencyKey string    `json:"idempotency_key" gorm:"uniqueIndex"`
-	Status         string    `json:"status"` // RESERVED, SHIPPED, RELEASED, EXPIRED
+	IdempotencyKey string    `json:"idempotency_key" gorm:"uniqueIndex:idx_reservation_item,priority:1"`
+	Status         string    `json:"status"` // RESERVED, CONFIRMED, SHIPPED, RELEASED, EXPIRED
+	CreatedBy      string    `json:"created_by" gorm:"size:64;index"`
 	ReservedAt     time.Time `json:"reserved_at"`
 	ExpiresAt      time.Time `json:"expires_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// BatchReservationItem is one product line in a batch reservation
+type BatchReservationItem struct {
+	ProductId int    `json:"product_id" binding:"required"`
+	Quantity  int    `json:"quantity" binding:"required,min=1"`
+	Warehouse string `json:"warehouse,omitempty"`
+}
+
+// BatchReservationRequest represents an all-or-nothing reservation of several products for one order
+type BatchReservationRequest struct {
+	Items          []BatchReservationItem `json:"items" binding:"required,min=1,dive"`
+	IdempotencyKey string                 `json:"idempotency_key"` // may come from the Idempotency-Key header instead
+	OrderId        string                 `json:"order_id" binding:"required"`
+	Region         string                 `json:"region,omitempty"` // drives the nearest routing policy
 }
 
 // ReleaseRequest represents a request to release reserved inventory
@@ -40,8 +63,54 @@ type ReleaseRequest struct {
 	OrderId        string `json:"order_id" binding:"required"`
 }
 
+// ConfirmRequest represents a request to commit reserved inventory to a paid order
+type ConfirmRequest struct {
+	IdempotencyKey string `json:"idempotency_key" binding:"required"`
+	OrderId        string `json:"order_id" binding:"required"`
+}
+
 // ShipRequest represents a request to ship reserved inventory
 type ShipRequest struct {
 	IdempotencyKey string `json:"idempotency_key" binding:"required"`
 	OrderId        string `json:"order_id" binding:"required"`
 }
+
+// ExtendReservationRequest represents a request to push back the expiry of an active reservation
+type ExtendReservationRequest struct {
+	IdempotencyKey string `json:"idempotency_key" binding:"required"`
+	OrderId        string `json:"order_id" binding:"required"`
+}
+
+// BulkAvailabilityRequest asks for the availability of several products at once
+type BulkAvailabilityRequest struct {
+	ProductIds []int `json:"product_ids" binding:"required,min=1,max=100"`
+}
+
+// ReceiveRequest records stock arriving from a supplier
+type ReceiveRequest struct {
+	ProductId int    `json:"product_id" binding:"required"`
+	Warehouse string `json:"warehouse" binding:"required"`
+	Quantity  int    `json:"quantity" binding:"required,min=1"`
+	Reference string `json:"reference"`
+}
+
+// InventoryReceipt is the audit record of a stock receipt
+type InventoryReceipt struct {
+	ID          int       `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	InventoryId int       `json:"inventory_id" gorm:"index"`
+	ProductId   int       `json:"product_id" gorm:"index"`
+	Warehouse   string    `json:"warehouse"`
+	Quantity    int       `json:"quantity"`
+	Reference   string    `json:"reference"`
+	ReceivedAt  time.Time `json:"received_at"`
+}
+
+// Warehouse is an entry in the warehouse master list; inventory and reservations refer to it by code
+type Warehouse struct {
+	Code      string    `json:"code" gorm:"primaryKey"`
+	Name      string    `json:"name"`
+	Region    string    `json:"region" gorm:"index"`
+	Active    bool      `json:"active" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
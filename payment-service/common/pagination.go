@@ -0,0 +1,66 @@
+package common
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Page sizes shared by every list endpoint
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// PageResponse is the body of every paginated list reply
+type PageResponse struct {
+	Items      any   `json:"items"`
+	Total      int64 `json:"total" example:"137"`
+	Page       int   `json:"page" example:"1"`
+	Limit      int   `json:"limit" example:"20"`
+	TotalPages int64 `json:"total_pages" example:"7"`
+}
+
+// Paginate reads limit and page (or a raw offset) from the query string. Missing, non-numeric,
+// zero or negative values fall back to the defaults and limit is capped at MaxPageSize.
+func Paginate(c *gin.Context) (limit, offset int) {
+	limit = DefaultPageSize
+	if parsed, err := strconv.Atoi(c.Query("limit")); err == nil && parsed > 0 {
+		limit = min(parsed, MaxPageSize)
+	}
+
+	if parsed, err := strconv.Atoi(c.Query("offset")); err == nil && parsed >= 0 {
+		return limit, parsed
+	}
+
+	page := 1
+	if parsed, err := strconv.Atoi(c.Query("page")); err == nil && parsed > 0 {
+		page = parsed
+	}
+	return limit, (page - 1) * limit
+}
+
+// NewPageResponse wraps one page of items with the paging metadata for limit and offset
+func NewPageResponse(items any, total int64, limit, offset int) PageResponse {
+	return PageResponse{
+		Items:      items,
+		Total:      total,
+		Page:       offset/limit + 1,
+		Limit:      limit,
+		TotalPages: (total + int64(limit) - 1) / int64(limit),
+	}
+}
+
+// WithLegacyKey returns the page with its items also under key, the field the endpoint listed them in
+// before it adopted PageResponse, plus their count, so existing clients keep working
+func (p PageResponse) WithLegacyKey(key string, count int) gin.H {
+	return gin.H{
+		key:           p.Items,
+		"count":       count,
+		"items":       p.Items,
+		"total":       p.Total,
+		"page":        p.Page,
+		"limit":       p.Limit,
+		"total_pages": p.TotalPages,
+	}
+}
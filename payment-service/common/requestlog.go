@@ -0,0 +1,68 @@
+package common
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// RequestIdHeader carries the correlation ID between services
+const RequestIdHeader = "X-Request-ID"
+
+// RequestIdKey is the gin context key holding the request's correlation ID
+const RequestIdKey = "request_id"
+
+// maxRequestIdLength bounds IDs accepted from callers so they can't flood the logs
+const maxRequestIdLength = 128
+
+// RequestLogger reuses the caller's X-Request-ID (or generates one), echoes it on the response
+// and logs one structured line per request once the handler chain finishes.
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestId := c.GetHeader(RequestIdHeader)
+		if requestId == "" || len(requestId) > maxRequestIdLength {
+			requestId = newRequestId()
+		}
+		c.Set(RequestIdKey, requestId)
+		c.Header(RequestIdHeader, requestId)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		entry := log.WithFields(log.Fields{
+			"request_id": requestId,
+			"method":     c.Request.Method,
+			"path":       c.Request.URL.Path,
+			"status":     status,
+			"latency_ms": time.Since(start).Milliseconds(),
+			"client_ip":  c.ClientIP(),
+		})
+		switch {
+		case status >= 500:
+			entry.Error("request completed")
+		case status >= 400:
+			entry.Warn("request completed")
+		default:
+			entry.Info("request completed")
+		}
+	}
+}
+
+// RequestId returns the correlation ID set by RequestLogger, or "" outside a logged request
+func RequestId(c *gin.Context) string {
+	return c.GetString(RequestIdKey)
+}
+
+// newRequestId returns a random 128-bit ID encoded as hex
+func newRequestId() string {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(raw)
+}
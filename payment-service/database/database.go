@@ -17,6 +17,9 @@ var (
 	err  error
 )
 
+// defaultHost is used when neither the config file nor DB_HOST names a database host
+const defaultHost = "postgres_main"
+
 type Repository struct {
 	Database *gorm.DB
 }
@@ -38,7 +41,8 @@ func SetupDB(configuration *common.Configuration) error {
 	if host != "" {
 		log.Infof("Host IP is %v", host)
 	} else {
-		log.Error("Host is Empty in Env Variable")
+		host = defaultHost
+		log.Warnf("Host is Empty in config and DB_HOST, falling back to %v", host)
 	}
 
 	// data source name
@@ -52,18 +56,9 @@ func SetupDB(configuration *common.Configuration) error {
 	)
 
 	if driver == "postgres" { // Postgres DB
-		for i := 0; i < 10; i++ {
-			db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
-			if err == nil {
-				log.Infof("Successfully connected to DB on attempt %d", i+1)
-				break
-			}
-			log.Warnf("Failed to connect to DB (attempt %d/10): %v", i+1, err)
-			time.Sleep(5 * time.Second)
-		}
-
+		db, err = openWithRetry(postgres.Open(dsn), &gorm.Config{TranslateError: true}, configuration.Database)
 		if err != nil {
-			log.Fatalf("Could not connect to database after 10 attempts: %v", err)
+			return err
 		}
 	}
 
@@ -93,3 +88,15 @@ func migrateModels() {
 func GetDB() *gorm.DB {
 	return Repo.Database
 }
+
+// LinkLegacyRefunds sets original_payment_id on refund rows recorded before the column existed. Those
+// refunds were named REF_<payment reference>_<suffix>, so each is matched to the payment with the longest
+// reference that prefixes it.
+func LinkLegacyRefunds() error {
+	return Repo.Database.Exec(`UPDATE payment_models AS r SET original_payment_id = (
+			SELECT p.payment_id FROM payment_models AS p
+			WHERE p.amount > 0 AND LEFT(r.reference, LENGTH(p.reference) + 5) = 'REF_' || p.reference || '_'
+			ORDER BY LENGTH(p.reference) DESC
+			LIMIT 1)
+		WHERE r.amount < 0 AND r.original_payment_id IS NULL AND r.reference LIKE 'REF%'`).Error
+}
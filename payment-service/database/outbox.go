@@ -0,0 +1,209 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/PoojaSrinivasan18/payment-service/common"
+
+	"github.com/apex/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Outbox event statuses
+const (
+	OutboxPending  = "PENDING"
+	OutboxInFlight = "IN_FLIGHT" // claimed by a publisher; next_attempt_at holds the claim's expiry
+	OutboxSent     = "SENT"
+	OutboxFailed   = "FAILED"
+)
+
+const (
+	defaultOutboxInterval    = 5 * time.Second
+	defaultOutboxMaxAttempts = 10
+	defaultOutboxBatchSize   = 50
+	maxOutboxBackoff         = 5 * time.Minute
+
+	// outboxClaimLease must outlast delivering a whole batch (50 events at the 5s client timeout);
+	// events still IN_FLIGHT after it, e.g. because the publisher died, are claimed again
+	outboxClaimLease = 5 * time.Minute
+)
+
+// outboxClient is used to deliver outbox events
+var outboxClient = &http.Client{Timeout: 5 * time.Second}
+
+// OutboxEvent is a cross-service call recorded in the same transaction as the change it announces.
+// The publisher POSTs Payload to Url until it gets a 2xx, so delivery is at least once.
+type OutboxEvent struct {
+	Id            int64      `json:"id" gorm:"primaryKey;autoIncrement:true"`
+	EventType     string     `json:"event_type" gorm:"size:64;index"`
+	Url           string     `json:"url"`
+	Payload       string     `json:"payload" gorm:"type:text"`
+	RequestId     string     `json:"request_id"`
+	Status        string     `json:"status" gorm:"size:16;index:idx_outbox_due,priority:1"`
+	Attempts      int        `json:"attempts"`
+	MaxAttempts   int        `json:"max_attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"index:idx_outbox_due,priority:2"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// EnqueueEvent records an event to POST to url once tx commits. Pass the state change's own transaction
+// so the event is saved if and only if the change is. maxAttempts of 0 uses outbox.maxattempts.
+func EnqueueEvent(tx *gorm.DB, eventType string, url string, payload interface{}, requestId string, maxAttempts int) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if maxAttempts <= 0 {
+		_, maxAttempts = outboxSettings()
+	}
+
+	return tx.Create(&OutboxEvent{
+		EventType:     eventType,
+		Url:           url,
+		Payload:       string(body),
+		RequestId:     requestId,
+		Status:        OutboxPending,
+		MaxAttempts:   maxAttempts,
+		NextAttemptAt: time.Now(),
+	}).Error
+}
+
+// StartOutboxPublisher delivers pending outbox events in the background until ctx is cancelled
+func StartOutboxPublisher(ctx context.Context, service string) {
+	interval, _ := outboxSettings()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.WithField("service", service).Info("Outbox publisher stopped")
+				return
+			case <-ticker.C:
+				publishOutbox(service, interval)
+			}
+		}
+	}()
+	log.WithField("service", service).Info("Outbox publisher started")
+}
+
+// publishOutbox sends one batch of due events. The batch is claimed in a short transaction and
+// delivered outside it, so no row lock or connection is held while waiting on HTTP calls.
+func publishOutbox(service string, interval time.Duration) {
+	events, err := claimOutboxBatch()
+	if err != nil {
+		log.Errorf("Outbox claim failed: %v", err)
+		return
+	}
+
+	for i := range events {
+		event := &events[i]
+		updates := map[string]interface{}{}
+
+		permanent, err := deliverOutboxEvent(*event)
+		switch {
+		case err == nil:
+			updates["status"] = OutboxSent
+			updates["sent_at"] = time.Now()
+			updates["last_error"] = ""
+		case permanent || event.Attempts >= event.MaxAttempts:
+			updates["status"] = OutboxFailed
+			updates["last_error"] = err.Error()
+			log.WithField("service", service).Errorf("Outbox event %d (%s) failed after %d attempts: %v", event.Id, event.EventType, event.Attempts, err)
+		default:
+			updates["status"] = OutboxPending
+			updates["last_error"] = err.Error()
+			updates["next_attempt_at"] = time.Now().Add(min(interval<<event.Attempts, maxOutboxBackoff))
+			log.WithField("service", service).Warnf("Outbox event %d (%s) attempt %d failed: %v", event.Id, event.EventType, event.Attempts, err)
+		}
+
+		// Only the claim holder may settle the event; if its lease ran out another publisher owns it now
+		if err := GetDB().Model(&OutboxEvent{}).
+			Where("id = ? AND status = ?", event.Id, OutboxInFlight).
+			Updates(updates).Error; err != nil {
+			log.Errorf("Failed to update outbox event %d: %v", event.Id, err)
+		}
+	}
+}
+
+// claimOutboxBatch marks up to a batch of due events IN_FLIGHT and counts the attempt. Rows are locked
+// with SKIP LOCKED while claiming, so several replicas can publish from the same table without
+// claiming an event twice.
+func claimOutboxBatch() ([]OutboxEvent, error) {
+	var events []OutboxEvent
+	err := GetDB().Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ? AND next_attempt_at <= ?", []string{OutboxPending, OutboxInFlight}, now).
+			Order("id asc").
+			Limit(defaultOutboxBatchSize).
+			Find(&events).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		ids := make([]int64, len(events))
+		for i := range events {
+			ids[i] = events[i].Id
+			events[i].Attempts++
+		}
+		return tx.Model(&OutboxEvent{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"status":          OutboxInFlight,
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": now.Add(outboxClaimLease),
+		}).Error
+	})
+	return events, err
+}
+
+// deliverOutboxEvent POSTs an event's payload. A 4xx other than 408 or 429 won't succeed on retry,
+// so it is reported as permanent.
+func deliverOutboxEvent(event OutboxEvent) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, event.Url, bytes.NewReader([]byte(event.Payload)))
+	if err != nil {
+		return true, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if event.RequestId != "" {
+		req.Header.Set(common.RequestIdHeader, event.RequestId)
+	}
+
+	resp, err := outboxClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	permanent := resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests
+	return permanent, fmt.Errorf("%s responded with status %d", event.Url, resp.StatusCode)
+}
+
+// outboxSettings returns the publisher interval and default attempt limit
+func outboxSettings() (time.Duration, int) {
+	interval, maxAttempts := defaultOutboxInterval, defaultOutboxMaxAttempts
+	if config := common.GetConfig(); config != nil {
+		if config.Outbox.Interval > 0 {
+			interval = config.Outbox.Interval
+		}
+		if config.Outbox.MaxAttempts > 0 {
+			maxAttempts = config.Outbox.MaxAttempts
+		}
+	}
+	return interval, maxAttempts
+}
@@ -1,6 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/PoojaSrinivasan18/payment-service/auth"
 	"github.com/PoojaSrinivasan18/payment-service/common"
 	"github.com/PoojaSrinivasan18/payment-service/database"
 	"github.com/PoojaSrinivasan18/payment-service/model"
@@ -8,11 +16,17 @@ import (
 
 	"github.com/apex/log"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 func main() {
 	log.Info("Starting Payment Service")
 
+	// Tokens only attribute payments to a user here, so run without them rather than refuse to start
+	if err := auth.LoadSecret(); err != nil {
+		log.Warnf("JWT secret not loaded, payments will be recorded as the system actor: %v", err)
+	}
+
 	err := common.ConfigSetup("config/dbconfig.yaml")
 	if err != nil {
 		log.Errorf("ConfigSetup failed: %v", err)
@@ -36,32 +50,101 @@ func main() {
 
 	log.Infof(" Running AutoMigrate...")
 	database.GetDB().Exec("SET search_path TO payment;")
-	err = database.GetDB().AutoMigrate(&model.PaymentModel{})
+	err = database.GetDB().AutoMigrate(&model.PaymentModel{}, &database.OutboxEvent{})
 	if err != nil {
 		log.Errorf("AutoMigrate failed: %v", err)
 	} else {
 		log.Infof(" Migration successful!")
 	}
 
-	router := gin.Default()
+	// Refund totals are summed by original_payment_id, so older refunds need the link filled in
+	if err := database.LinkLegacyRefunds(); err != nil {
+		log.Errorf("Linking legacy refunds failed: %v", err)
+	}
+
+	// Cancelled on SIGINT/SIGTERM so background jobs and the server can wind down
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Start stuck payment sweeper
+	payment_service.StartSweeperJob(ctx)
+
+	// Deliver ship requests recorded in the outbox
+	database.StartOutboxPublisher(ctx, "payment")
+
+	// JSON bodies with unknown fields are rejected, so a misspelled field is a 400 rather than silently ignored
+	binding.EnableDecoderDisallowUnknownFields = true
+
+	router := setupRouter(configuration)
+
+	//:: Note: For local testing use below
+	//router.Run("localhost:3000")
+
+	//:: For Docker use below
+	server := &http.Server{
+		Addr:    ":8002",
+		Handler: router,
+	}
 
-	// Add health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "healthy", "service": "payment"})
-	})
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("HTTP server failed: %v", err)
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+	log.Info("Shutdown signal received, draining HTTP server")
+
+	// Give in-flight charges time to settle before the process exits
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Errorf("HTTP server shutdown failed: %v", err)
+	} else {
+		log.Info("HTTP server stopped")
+	}
+
+	log.Info("Payment service shut down")
+}
+
+// setupRouter builds the router with the service's middleware and every route
+func setupRouter(configuration *common.Configuration) *gin.Engine {
+	// RequestLogger replaces gin's default access log with one structured line per request
+	router := gin.New()
+	router.Use(gin.Recovery(), common.RequestLogger())
+	router.Use(common.CORS(configuration.Cors.AllowedOrigins))
+	router.Use(common.LimitRequestBody(configuration.Request.MaxBodyBytes, configuration.Request.MaxUploadBytes))
+
+	// Health checks: /live for liveness, /ready (and /health) for readiness, which pings the database
+	router.GET("/live", database.LivenessCheck("payment"))
+	router.GET("/ready", database.ReadinessCheck("payment"))
+	router.GET("/health", database.ReadinessCheck("payment"))
+
+	// Connection pool stats, for diagnosing pool exhaustion under load
+	router.GET("/debug/dbstats", database.DbStats("payment"))
+	router.GET("/debug/gateway", payment_service.GatewaySettings)
 
 	// API versioning with /v1
 	v1 := router.Group("/v1")
 	{
+		v1.POST("/admin/reset", database.ResetTables("payment", &model.PaymentModel{}, &database.OutboxEvent{}))
+		v1.GET("/payments", payment_service.ListPayments)
+		v1.GET("/payments/report", payment_service.GetPaymentReport)
+		v1.GET("/payments/order/:orderId", payment_service.GetPaymentsByOrder)
+		v1.GET("/payments/customer/:customerId", auth.RequireSelfOrAdmin("customerId"), payment_service.GetCustomerPaymentSummary)
 		v1.GET("/payments/:id", payment_service.GetPaymentById)
 		v1.POST("/payments/charge", payment_service.ChargePayment)
+		v1.POST("/payments/charge/batch", payment_service.ChargePaymentBatch)
+		v1.POST("/payments/webhook", payment_service.PaymentWebhook)
+		v1.POST("/checkout", payment_service.Checkout)
+		v1.POST("/payments/seed", database.RequireReset("payment"), payment_service.SeedPayments)
+		v1.POST("/payments/authorize", payment_service.AuthorizePayment)
+		v1.POST("/payments/:id/capture", payment_service.CapturePayment)
 		v1.POST("/payments/:id/refund", payment_service.RefundPayment)
 		v1.DELETE("/payments/:id", payment_service.DeletePayment)
 	}
 
-	//:: Note: For local testing use below
-	//router.Run("localhost:3000")
-
-	//:: For Docker use below
-	router.Run(":8002")
+	return router
 }
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/PoojaSrinivasan18/payment-service/common"
+	"github.com/PoojaSrinivasan18/payment-service/database"
+	"github.com/PoojaSrinivasan18/payment-service/database/dbtest"
+	"github.com/PoojaSrinivasan18/payment-service/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDeletePaymentRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := dbtest.Open(t, &model.PaymentModel{}, &database.OutboxEvent{})
+	router := setupRouter(&common.Configuration{})
+
+	payment := model.PaymentModel{OrderId: "ORD-1", Amount: 10, Status: "COMPLETED", IdempotencyKey: "key-1"}
+	if err := db.Create(&payment).Error; err != nil {
+		t.Fatalf("create payment: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{"existing payment", "/v1/payments/" + strconv.Itoa(payment.PaymentId), http.StatusOK},
+		{"already deleted", "/v1/payments/" + strconv.Itoa(payment.PaymentId), http.StatusNotFound},
+		{"unknown payment", "/v1/payments/999", http.StatusNotFound},
+		{"non-numeric id", "/v1/payments/abc", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, tt.path, nil))
+			if w.Code != tt.status {
+				t.Fatalf("DELETE %s: got %d, want %d: %s", tt.path, w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+
+	var count int64
+	db.Model(&model.PaymentModel{}).Where("payment_id = ?", payment.PaymentId).Count(&count)
+	if count != 0 {
+		t.Fatalf("payment %d still exists after delete", payment.PaymentId)
+	}
+}
@@ -3,29 +3,91 @@ package model
 import "time"
 
 type PaymentModel struct {
-	PaymentId      int       `json:"payment_id" gorm:"primaryKey;autoIncrement:true"`
-	OrderId        string    `json:"order_id"`
-	Amount         float64   `json:"amount"`
-	Method         string    `json:"method"`
-	Status         string    `json:"status"`
-	Reference      string    `json:"reference"`
-	IdempotencyKey string    `json:"idempotency_key" gorm:"uniqueIndex"`
-	CustomerId     int       `json:"customer_id"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	PaymentId            int        `json:"payment_id" gorm:"primaryKey;autoIncrement:true"`
+	OrderId              string     `json:"order_id"`
+	Amount               float64    `json:"amount"`
+	Currency             string     `json:"currency" gorm:"size:3;default:USD"`
+	AuthorizedAmount     float64    `json:"authorized_amount,omitempty"`
+	RefundedAmount       float64    `json:"refunded_amount"`
+	OriginalPaymentId    *int       `json:"original_payment_id,omitempty" gorm:"index"` // set on refund rows
+	Method               string     `json:"method"`
+	Status               string     `json:"status"`
+	FailureReason        string     `json:"failure_reason,omitempty"`
+	Reference            string     `json:"reference"`
+	IdempotencyKey       string     `json:"idempotency_key" gorm:"uniqueIndex"`
+	ReservationKey       string     `json:"reservation_key,omitempty"` // inventory reservation to ship; empty means IdempotencyKey
+	GatewayTransactionId string     `json:"gateway_transaction_id"`
+	CustomerId           int        `json:"customer_id"`
+	CreatedBy            string     `json:"created_by" gorm:"size:64;index"`
+	CreatedAt            time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	AuthorizedAt         *time.Time `json:"authorized_at,omitempty"`
+	UpdatedAt            time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
 // ChargeRequest represents a payment charge request
 type ChargeRequest struct {
 	OrderId        string  `json:"order_id" binding:"required"`
 	Amount         float64 `json:"amount" binding:"required,gt=0"`
+	Currency       string  `json:"currency,omitempty"`
 	CustomerId     int     `json:"customer_id,omitempty"`
 	Method         string  `json:"method"`
-	IdempotencyKey string  `json:"idempotency_key" binding:"required"`
+	IdempotencyKey string  `json:"idempotency_key"`           // may come from the Idempotency-Key header instead
+	ReservationKey string  `json:"reservation_key,omitempty"` // idempotency key of the inventory reservation, when it differs
+}
+
+// BatchChargeLine is one seller's share of a split marketplace charge
+type BatchChargeLine struct {
+	OrderId    string  `json:"order_id" binding:"required"`
+	Amount     float64 `json:"amount" binding:"required,gt=0"`
+	Currency   string  `json:"currency,omitempty"`
+	CustomerId int     `json:"customer_id,omitempty"`
+	Method     string  `json:"method"`
+
+	// ReservationKey names the inventory reservation the line ships; defaults to the batch's idempotency key
+	ReservationKey string `json:"reservation_key,omitempty"`
+}
+
+// BatchChargeRequest charges every line or none of them under one parent idempotency key
+type BatchChargeRequest struct {
+	IdempotencyKey string            `json:"idempotency_key" binding:"required"`
+	Charges        []BatchChargeLine `json:"charges" binding:"required,min=1,max=50,dive"`
+}
+
+// CheckoutItem is one product line of a checkout, forwarded to the inventory batch reservation
+type CheckoutItem struct {
+	ProductId int    `json:"product_id" binding:"required"`
+	Quantity  int    `json:"quantity" binding:"required,min=1"`
+	Warehouse string `json:"warehouse,omitempty"`
+}
+
+// CheckoutRequest reserves, charges and ships an order in one call; the idempotency key is shared by every step
+type CheckoutRequest struct {
+	OrderId        string         `json:"order_id" binding:"required"`
+	Items          []CheckoutItem `json:"items" binding:"required,min=1,dive"`
+	Amount         float64        `json:"amount" binding:"required,gt=0"`
+	Currency       string         `json:"currency,omitempty"`
+	CustomerId     int            `json:"customer_id,omitempty"`
+	Method         string         `json:"method"`
+	IdempotencyKey string         `json:"idempotency_key" binding:"required"`
 }
 
 // RefundRequest represents a payment refund request
 type RefundRequest struct {
+	Amount         float64 `json:"amount,omitempty"`
+	Currency       string  `json:"currency,omitempty"`
+	Reason         string  `json:"reason"`
+	IdempotencyKey string  `json:"idempotency_key,omitempty"`
+}
+
+// CaptureRequest represents a capture of a previously authorized payment
+type CaptureRequest struct {
 	Amount float64 `json:"amount,omitempty"`
-	Reason string  `json:"reason"`
+}
+
+// WebhookEvent is a payment gateway callback reporting the final outcome of a charge
+type WebhookEvent struct {
+	TransactionId string `json:"transaction_id"`
+	Reference     string `json:"reference"`
+	Status        string `json:"status"`
+	FailureReason string `json:"failure_reason,omitempty"`
 }
@@ -0,0 +1,209 @@
+package payment_service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/PoojaSrinivasan18/payment-service/auth"
+	"github.com/PoojaSrinivasan18/payment-service/common"
+	"github.com/PoojaSrinivasan18/payment-service/database"
+	"github.com/PoojaSrinivasan18/payment-service/model"
+
+	"github.com/apex/log"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ChargePaymentBatch charges several lines, e.g. one per marketplace seller, as a unit. Every line is
+// charged before any is settled; only when all succeed are they marked COMPLETED, together with their
+// payment.completed events, in one transaction. Gateway charges can't be rolled back, so if any line is
+// declined the lines already charged are refunded and marked REVERSED, and nothing is shipped.
+func ChargePaymentBatch(c *gin.Context) {
+	var req model.BatchChargeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("JSON binding error: %v", err)
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid request", err.Error())
+		return
+	}
+
+	currencies := make([]string, len(req.Charges))
+	for i, line := range req.Charges {
+		currency, ok := normalizeCurrency(line.Currency)
+		if !ok {
+			common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Unsupported currency", gin.H{
+				"line":     i + 1,
+				"currency": line.Currency,
+			})
+			return
+		}
+		currencies[i] = currency
+	}
+
+	// Each line gets its own key derived from the parent, so retries find the earlier batch
+	keys := make([]string, len(req.Charges))
+	for i := range req.Charges {
+		keys[i] = batchLineKey(req.IdempotencyKey, i)
+	}
+
+	db := database.GetDB()
+	if respondWithExistingBatch(c, db, keys) {
+		return
+	}
+
+	gateway := gatewayFor(c)
+	actor := auth.Actor(c)
+	requestId := common.RequestId(c)
+
+	payments := make([]model.PaymentModel, 0, len(req.Charges))
+	outcomes := make([]chargeOutcome, 0, len(req.Charges))
+	for i, line := range req.Charges {
+		// Line keys are derived, so no reservation is made under them; lines ship the batch key's reservation unless they name one
+		reservationKey := line.ReservationKey
+		if reservationKey == "" {
+			reservationKey = req.IdempotencyKey
+		}
+
+		draft := newPayment(model.ChargeRequest{
+			OrderId:        line.OrderId,
+			Amount:         line.Amount,
+			CustomerId:     line.CustomerId,
+			Method:         line.Method,
+			IdempotencyKey: keys[i],
+			ReservationKey: reservationKey,
+		}, currencies[i], actor)
+		// The line number keeps references unique even when generated in the same instant
+		draft.Reference = fmt.Sprintf("%s_L%d", draft.Reference, i+1)
+
+		payment, outcome, err := startCharge(db, gateway, draft)
+		if err != nil {
+			// A concurrent retry of the same batch loses on the first line, before anything is charged
+			if i == 0 && errors.Is(err, gorm.ErrDuplicatedKey) && respondWithExistingBatch(c, db, keys) {
+				return
+			}
+			log.Errorf("Failed to save batch charge line %d: %v", i+1, err)
+			reverseBatchCharges(db, gateway, payments, outcomes, requestId)
+			common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Payment processing failed")
+			return
+		}
+
+		if !outcome.succeeded() {
+			payment, err = settlePayment(db, payment.PaymentId, outcome.apply, nil)
+			if err != nil {
+				log.Errorf("Failed to record declined batch charge line %d: %v", i+1, err)
+			}
+			log.Errorf("Batch charge line %d declined: %s", i+1, payment.FailureReason)
+			lines := reverseBatchCharges(db, gateway, payments, outcomes, requestId)
+			lines = append(lines, gin.H{"line": i + 1, "status": payment.Status, "failure_reason": payment.FailureReason, "reference": payment.Reference})
+			for j := i + 1; j < len(req.Charges); j++ {
+				lines = append(lines, gin.H{"line": j + 1, "status": "NOT_ATTEMPTED"})
+			}
+			common.RespondErrorWithDetails(c, http.StatusPaymentRequired, common.CodePaymentFailed, "Batch charge failed; no line was charged", gin.H{
+				"status":      "FAILED",
+				"failed_line": i + 1,
+				"lines":       lines,
+			})
+			return
+		}
+
+		payments = append(payments, payment)
+		outcomes = append(outcomes, outcome)
+	}
+
+	// Every line was charged: complete them all and queue their shipments in one transaction
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for i := range payments {
+			settled, err := settlePayment(tx, payments[i].PaymentId, outcomes[i].apply, func(tx *gorm.DB, payment model.PaymentModel) error {
+				return enqueuePaymentCompleted(tx, payment, requestId)
+			})
+			if err != nil {
+				return err
+			}
+			payments[i] = settled
+		}
+		return nil
+	})
+	if err != nil {
+		// The lines stay PROCESSING with the gateway holding the money; the sweeper and a retry see them
+		log.Errorf("Failed to complete batch charge %s: %v", req.IdempotencyKey, err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Payment processing failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Batch charge processed successfully",
+		"status":       "COMPLETED",
+		"payments":     payments,
+		"total_amount": batchTotal(payments),
+	})
+}
+
+// batchLineKey derives the idempotency key of one batch line from the parent key
+func batchLineKey(parentKey string, line int) string {
+	return fmt.Sprintf("%s#%d", parentKey, line+1)
+}
+
+// reverseBatchCharges refunds, through the gateway that charged them, lines that were charged but not yet
+// settled. Each is marked REVERSED with a payment.reversed event releasing its reservation, or COMPLETED
+// if the refund fails and the money is still taken. It reports each line's outcome.
+func reverseBatchCharges(db *gorm.DB, gateway PaymentGateway, payments []model.PaymentModel, outcomes []chargeOutcome, requestId string) []gin.H {
+	lines := make([]gin.H, 0, len(payments))
+	for i, payment := range payments {
+		result, err := gateway.Refund(payment.Amount, payment.Method, generateRefundReference(payment.Reference))
+		if err != nil || !result.Success {
+			log.Errorf("Failed to reverse batch charge %s: %v", payment.Reference, err)
+			if _, err := settlePayment(db, payment.PaymentId, outcomes[i].apply, nil); err != nil {
+				log.Errorf("Batch charge %s could not be recorded as charged: %v", payment.Reference, err)
+			}
+			lines = append(lines, gin.H{"line": i + 1, "status": "REVERSAL_FAILED", "reference": payment.Reference})
+			continue
+		}
+
+		_, err = settlePayment(db, payment.PaymentId, func(payment *model.PaymentModel) {
+			payment.GatewayTransactionId = outcomes[i].result.TransactionId
+			payment.Status = "REVERSED"
+		}, func(tx *gorm.DB, payment model.PaymentModel) error {
+			return enqueuePaymentReversed(tx, payment, requestId)
+		})
+		if err != nil {
+			// The money is back with the customer; only the record is behind
+			log.Errorf("Batch charge %s was refunded but could not be marked reversed: %v", payment.Reference, err)
+		}
+		lines = append(lines, gin.H{"line": i + 1, "status": "REVERSED", "reference": payment.Reference})
+	}
+	return lines
+}
+
+// respondWithExistingBatch replies with the payments already stored for a batch's line keys, if any
+func respondWithExistingBatch(c *gin.Context, db *gorm.DB, keys []string) bool {
+	var existing []model.PaymentModel
+	if err := db.Where("idempotency_key IN ?", keys).Order("payment_id asc").Find(&existing).Error; err != nil || len(existing) == 0 {
+		return false
+	}
+
+	status := existing[0].Status
+	for _, payment := range existing[1:] {
+		if payment.Status != status {
+			status = "MIXED"
+			break
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Batch charge already processed",
+		"status":       status,
+		"payments":     existing,
+		"total_amount": batchTotal(existing),
+		"idempotent":   true,
+	})
+	return true
+}
+
+// batchTotal sums the amounts of a batch's payments
+func batchTotal(payments []model.PaymentModel) float64 {
+	total := 0.0
+	for _, payment := range payments {
+		total += payment.Amount
+	}
+	return roundAmount(total)
+}
@@ -0,0 +1,74 @@
+package payment_service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PoojaSrinivasan18/payment-service/common"
+	"github.com/PoojaSrinivasan18/payment-service/database"
+	"github.com/PoojaSrinivasan18/payment-service/database/dbtest"
+	"github.com/PoojaSrinivasan18/payment-service/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestChargePaymentBatchAllOrNothing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		declineAmount float64
+		status        int
+		wantStatuses  []string
+		wantCompleted int64
+		wantReversed  int64
+		wantRefunds   int32
+	}{
+		{"all lines charged", 0, http.StatusOK, []string{"COMPLETED", "COMPLETED"}, 2, 0, 0},
+		{"second line declined", 20, http.StatusPaymentRequired, []string{"REVERSED", "FAILED"}, 0, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.Open(t, &model.PaymentModel{}, &database.OutboxEvent{})
+			gateway := &countingGateway{declineAmount: tt.declineAmount}
+			useGateway(t, gateway)
+			useConfig(t, &common.Configuration{Inventory: common.InventoryConfiguration{Url: "http://inventory.test"}})
+
+			router := gin.New()
+			router.POST("/v1/payments/charge/batch", ChargePaymentBatch)
+
+			body := `{"idempotency_key":"batch","charges":[{"order_id":"ORD-1","amount":10},{"order_id":"ORD-2","amount":20}]}`
+			req := httptest.NewRequest(http.MethodPost, "/v1/payments/charge/batch", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("got %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+
+			var payments []model.PaymentModel
+			db.Order("payment_id asc").Find(&payments)
+			if len(payments) != len(tt.wantStatuses) {
+				t.Fatalf("got %d payments, want %d", len(payments), len(tt.wantStatuses))
+			}
+			for i, payment := range payments {
+				if payment.Status != tt.wantStatuses[i] {
+					t.Errorf("line %d is %s, want %s", i+1, payment.Status, tt.wantStatuses[i])
+				}
+			}
+
+			// A declined batch must never have asked inventory to ship any line
+			var completed, reversed int64
+			db.Model(&database.OutboxEvent{}).Where("event_type = ?", EventPaymentCompleted).Count(&completed)
+			db.Model(&database.OutboxEvent{}).Where("event_type = ?", EventPaymentReversed).Count(&reversed)
+			if completed != tt.wantCompleted || reversed != tt.wantReversed {
+				t.Fatalf("got %d completed and %d reversed events, want %d and %d", completed, reversed, tt.wantCompleted, tt.wantReversed)
+			}
+			if refunds := gateway.refunds.Load(); refunds != tt.wantRefunds {
+				t.Fatalf("gateway refunded %d times, want %d", refunds, tt.wantRefunds)
+			}
+		})
+	}
+}
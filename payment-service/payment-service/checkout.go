@@ -0,0 +1,169 @@
+package payment_service
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/PoojaSrinivasan18/payment-service/auth"
+	"github.com/PoojaSrinivasan18/payment-service/common"
+	"github.com/PoojaSrinivasan18/payment-service/database"
+	"github.com/PoojaSrinivasan18/payment-service/model"
+
+	"github.com/apex/log"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Checkout statuses reported in the consolidated response
+const (
+	CheckoutCompleted         = "COMPLETED"
+	CheckoutReservationFailed = "RESERVATION_FAILED"
+	CheckoutPaymentFailed     = "PAYMENT_FAILED"
+	CheckoutShipmentPending   = "SHIPMENT_PENDING"
+)
+
+// Checkout reserves the order's items, charges the payment and ships the reservation, releasing the
+// reservation if the charge fails. Every step uses the request's idempotency key, so a retry resumes
+// from the recorded payment instead of reserving or charging twice.
+func Checkout(c *gin.Context) {
+	var req model.CheckoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("JSON binding error: %v", err)
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid request", err.Error())
+		return
+	}
+
+	currency, ok := normalizeCurrency(req.Currency)
+	if !ok {
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Unsupported currency", gin.H{"currency": req.Currency})
+		return
+	}
+
+	baseUrl := inventoryServiceUrl()
+	if baseUrl == "" {
+		common.RespondError(c, http.StatusServiceUnavailable, common.CodeUpstream, "Inventory service URL is not configured")
+		return
+	}
+
+	db := database.GetDB()
+	requestId := common.RequestId(c)
+
+	// A recorded payment means an earlier attempt got past reservation; pick up from there
+	var payment model.PaymentModel
+	resumed := db.Where("idempotency_key = ?", req.IdempotencyKey).First(&payment).Error == nil
+	if resumed && payment.OrderId != req.OrderId {
+		common.RespondErrorWithDetails(c, http.StatusConflict, common.CodeConflict, "Idempotency key already used for another order", gin.H{
+			"order_id": payment.OrderId,
+		})
+		return
+	}
+
+	if !resumed {
+		status, err := postInventory(baseUrl, "/v1/inventory/reserve/batch", inventoryBatchReserveRequest{
+			Items:          req.Items,
+			IdempotencyKey: req.IdempotencyKey,
+			OrderId:        req.OrderId,
+		}, requestId)
+		switch {
+		case err != nil:
+			log.Errorf("Checkout reservation call failed for order %s: %v", req.OrderId, err)
+			common.RespondErrorWithDetails(c, http.StatusBadGateway, common.CodeUpstream, "Inventory reservation failed", gin.H{"status": CheckoutReservationFailed})
+			return
+		case status == http.StatusConflict:
+			common.RespondErrorWithDetails(c, http.StatusConflict, common.CodeConflict, "Insufficient inventory for order", gin.H{"status": CheckoutReservationFailed})
+			return
+		case status != http.StatusOK:
+			common.RespondErrorWithDetails(c, http.StatusBadGateway, common.CodeUpstream, "Inventory reservation failed", gin.H{
+				"status":           CheckoutReservationFailed,
+				"inventory_status": status,
+			})
+			return
+		}
+
+		payment, err = chargeNewPayment(db, gatewayFor(c), newPayment(model.ChargeRequest{
+			OrderId:        req.OrderId,
+			Amount:         req.Amount,
+			Currency:       currency,
+			CustomerId:     req.CustomerId,
+			Method:         req.Method,
+			IdempotencyKey: req.IdempotencyKey,
+			ReservationKey: req.IdempotencyKey,
+		}, currency, auth.Actor(c)), nil)
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			// A concurrent retry recorded the payment first; continue from its outcome
+			err = db.Where("idempotency_key = ?", req.IdempotencyKey).First(&payment).Error
+			resumed = true
+		}
+		if err != nil {
+			log.Errorf("Checkout charge failed for order %s: %v", req.OrderId, err)
+			common.RespondErrorWithDetails(c, http.StatusInternalServerError, common.CodeInternal, "Payment processing failed", gin.H{
+				"status":      CheckoutPaymentFailed,
+				"reservation": releaseCheckoutReservation(baseUrl, req, requestId),
+			})
+			return
+		}
+	}
+
+	switch payment.Status {
+	case "COMPLETED":
+		status, err := postInventory(baseUrl, "/v1/inventory/ship", inventoryShipRequest{
+			IdempotencyKey: reservationKey(payment),
+			OrderId:        payment.OrderId,
+		}, requestId)
+
+		// On a retry, 404 means the earlier attempt already shipped the reservation
+		shipped := err == nil && (status == http.StatusOK || (resumed && status == http.StatusNotFound))
+		if !shipped {
+			// The charge stands; a retry with the same key ships without charging again
+			log.Warnf("Checkout ship call failed for payment %d (order %s): status %d, %v", payment.PaymentId, payment.OrderId, status, err)
+			c.JSON(http.StatusAccepted, gin.H{
+				"message":  "Payment completed, shipment pending; retry to ship",
+				"status":   CheckoutShipmentPending,
+				"order_id": payment.OrderId,
+				"payment":  payment,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":    "Checkout completed successfully",
+			"status":     CheckoutCompleted,
+			"order_id":   payment.OrderId,
+			"payment":    payment,
+			"idempotent": resumed,
+		})
+	case "FAILED":
+		common.RespondErrorWithDetails(c, http.StatusPaymentRequired, common.CodePaymentFailed, "Payment failed", gin.H{
+			"status":         CheckoutPaymentFailed,
+			"failure_reason": payment.FailureReason,
+			"payment":        payment,
+			"reservation":    releaseCheckoutReservation(baseUrl, req, requestId),
+		})
+	default:
+		common.RespondErrorWithDetails(c, http.StatusConflict, common.CodeConflict, "Payment for this checkout is not in a state that can ship", gin.H{
+			"payment_status": payment.Status,
+			"payment":        payment,
+		})
+	}
+}
+
+// releaseCheckoutReservation compensates a failed charge by releasing the order's reservation and
+// reports what happened to it. A reservation that is no longer held was already released or expired.
+func releaseCheckoutReservation(baseUrl string, req model.CheckoutRequest, requestId string) string {
+	status, err := postInventory(baseUrl, "/v1/inventory/release", inventoryShipRequest{
+		IdempotencyKey: req.IdempotencyKey,
+		OrderId:        req.OrderId,
+	}, requestId)
+	switch {
+	case err != nil:
+		log.Errorf("Checkout release call failed for order %s: %v", req.OrderId, err)
+		return "RELEASE_FAILED"
+	case status == http.StatusOK:
+		return "RELEASED"
+	case status == http.StatusNotFound:
+		return "NOT_HELD"
+	default:
+		log.Errorf("Checkout release for order %s got inventory status %d", req.OrderId, status)
+		return "RELEASE_FAILED"
+	}
+}
@@ -0,0 +1,25 @@
+package payment_service
+
+import "strings"
+
+// DefaultCurrency is applied when a request omits the currency
+const DefaultCurrency = "USD"
+
+// supportedCurrencies lists the ISO 4217 codes accepted by the payment service
+var supportedCurrencies = map[string]bool{
+	"AED": true, "AUD": true, "BRL": true, "CAD": true, "CHF": true,
+	"CNY": true, "DKK": true, "EUR": true, "GBP": true, "HKD": true,
+	"IDR": true, "INR": true, "JPY": true, "KRW": true, "MXN": true,
+	"MYR": true, "NOK": true, "NZD": true, "PHP": true, "PLN": true,
+	"SAR": true, "SEK": true, "SGD": true, "THB": true, "TRY": true,
+	"USD": true, "ZAR": true,
+}
+
+// normalizeCurrency upper-cases a currency code and defaults it to USD when empty
+func normalizeCurrency(currency string) (string, bool) {
+	code := strings.ToUpper(strings.TrimSpace(currency))
+	if code == "" {
+		return DefaultCurrency, true
+	}
+	return code, supportedCurrencies[code]
+}
@@ -0,0 +1,176 @@
+package payment_service
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PoojaSrinivasan18/payment-service/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GatewayResult is the outcome reported by a payment gateway
+type GatewayResult struct {
+	Success       bool
+	TransactionId string
+	FailureReason string
+}
+
+// Failure reasons reported by gateways when a request is declined
+const (
+	FailureInsufficientFunds = "insufficient_funds"
+	FailureCardDeclined      = "card_declined"
+	FailureInvalidAmount     = "invalid_amount"
+	FailureGatewayError      = "gateway_error"
+)
+
+// PaymentGateway is implemented by anything that can move money for a payment
+type PaymentGateway interface {
+	Charge(amount float64, method string, ref string) (GatewayResult, error)
+	Authorize(amount float64, method string, ref string) (GatewayResult, error)
+	Refund(amount float64, method string, ref string) (GatewayResult, error)
+}
+
+// Gateway is the gateway used by the payment handlers; swap it to plug in a real processor
+var Gateway PaymentGateway = SimulatedGateway{}
+
+// TestScenarioHeader forces a simulated outcome for one request when test scenarios are enabled
+const TestScenarioHeader = "X-Test-Scenario"
+
+// ScenarioSuccess is the test scenario that always approves
+const ScenarioSuccess = "success"
+
+// testScenarioCents maps magic amount cents to the decline they force when test scenarios are enabled
+var testScenarioCents = map[int]string{
+	1: FailureCardDeclined,
+	2: FailureInsufficientFunds,
+	3: FailureGatewayError,
+}
+
+// SimulatedGateway is the default gateway. It approves the configured share of requests (95% by default).
+// With test scenarios enabled it is deterministic: Scenario, or else the amount's magic cents, picks the
+// outcome and everything else is approved.
+type SimulatedGateway struct {
+	Scenario string
+}
+
+// gatewayFor returns the gateway for a request, honouring the X-Test-Scenario header on the simulator
+func gatewayFor(c *gin.Context) PaymentGateway {
+	scenario := strings.ToLower(strings.TrimSpace(c.GetHeader(TestScenarioHeader)))
+	if scenario == "" || !testScenariosEnabled() {
+		return Gateway
+	}
+	if _, ok := Gateway.(SimulatedGateway); !ok {
+		return Gateway
+	}
+	return SimulatedGateway{Scenario: scenario}
+}
+
+// gatewaySettings returns the simulator's success rate (clamped to 0-1), base latency and jitter
+func gatewaySettings() (successRate float64, latency, jitter time.Duration) {
+	config := common.GetConfig()
+	if config == nil {
+		return 0.95, 0, 0
+	}
+	return min(max(config.Gateway.SuccessRate, 0), 1), max(config.Gateway.Latency, 0), max(config.Gateway.LatencyJitter, 0)
+}
+
+// simulateLatency sleeps for the configured latency plus a random share of the jitter
+func simulateLatency() {
+	_, latency, jitter := gatewaySettings()
+	if jitter > 0 {
+		latency += time.Duration(rand.Int63n(int64(jitter) + 1))
+	}
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+}
+
+// GatewaySettings reports the simulator's active settings so load-test harnesses can confirm them
+func GatewaySettings(c *gin.Context) {
+	successRate, latency, jitter := gatewaySettings()
+	_, simulated := Gateway.(SimulatedGateway)
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"simulated":         simulated,
+		"test_scenarios":    testScenariosEnabled(),
+		"success_rate":      successRate,
+		"latency_ms":        latency.Milliseconds(),
+		"latency_jitter_ms": jitter.Milliseconds(),
+	})
+}
+
+// testScenariosEnabled reports whether forced outcomes are allowed; keep it off outside test and dev
+func testScenariosEnabled() bool {
+	config := common.GetConfig()
+	return config != nil && config.Gateway.TestScenarios
+}
+
+func (g SimulatedGateway) Charge(amount float64, method string, ref string) (GatewayResult, error) {
+	simulateLatency()
+	return g.process(amount, ref), nil
+}
+
+func (g SimulatedGateway) Authorize(amount float64, method string, ref string) (GatewayResult, error) {
+	simulateLatency()
+	return g.process(amount, ref), nil
+}
+
+func (g SimulatedGateway) Refund(amount float64, method string, ref string) (GatewayResult, error) {
+	simulateLatency()
+	if amount <= 0 {
+		return GatewayResult{Success: false, FailureReason: FailureInvalidAmount}, nil
+	}
+	// Magic cents only decide charges, so a forced refund needs an explicit scenario
+	if g.Scenario != "" {
+		if reason, forced := g.forcedFailure(amount); forced {
+			return GatewayResult{Success: false, FailureReason: reason}, nil
+		}
+	}
+	return GatewayResult{Success: true, TransactionId: generateTransactionId(ref)}, nil
+}
+
+// process simulates gateway processing
+func (g SimulatedGateway) process(amount float64, ref string) GatewayResult {
+	// Simulate different scenarios based on amount
+	if amount <= 0 {
+		return GatewayResult{Success: false, FailureReason: FailureInvalidAmount}
+	}
+
+	if testScenariosEnabled() {
+		if reason, forced := g.forcedFailure(amount); forced {
+			return GatewayResult{Success: false, TransactionId: generateTransactionId(ref), FailureReason: reason}
+		}
+		return GatewayResult{Success: true, TransactionId: generateTransactionId(ref)}
+	}
+
+	// Approve at the configured success rate (95% unless overridden)
+	if successRate, _, _ := gatewaySettings(); rand.Float64() >= successRate {
+		return GatewayResult{Success: false, TransactionId: generateTransactionId(ref), FailureReason: FailureCardDeclined}
+	}
+	return GatewayResult{Success: true, TransactionId: generateTransactionId(ref)}
+}
+
+// forcedFailure returns the decline a test scenario forces, if any
+func (g SimulatedGateway) forcedFailure(amount float64) (string, bool) {
+	if !testScenariosEnabled() {
+		return "", false
+	}
+	switch g.Scenario {
+	case ScenarioSuccess:
+		return "", false
+	case "":
+		reason, ok := testScenarioCents[int(math.Round(amount*100))%100]
+		return reason, ok
+	default:
+		return g.Scenario, true
+	}
+}
+
+// generateTransactionId creates a gateway transaction ID for a payment reference
+func generateTransactionId(ref string) string {
+	return fmt.Sprintf("SIM_%s_%d", ref, time.Now().UnixNano())
+}
@@ -0,0 +1,116 @@
+package payment_service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PoojaSrinivasan18/payment-service/common"
+	"github.com/PoojaSrinivasan18/payment-service/database"
+	"github.com/PoojaSrinivasan18/payment-service/model"
+
+	"github.com/apex/log"
+	"gorm.io/gorm"
+)
+
+// inventoryClient is used for outbound calls to the inventory service
+var inventoryClient = &http.Client{Timeout: 5 * time.Second}
+
+// inventoryShipRequest mirrors the inventory service's ship and release request bodies
+type inventoryShipRequest struct {
+	IdempotencyKey string `json:"idempotency_key"`
+	OrderId        string `json:"order_id"`
+}
+
+// inventoryBatchReserveRequest mirrors the inventory service's batch reservation body
+type inventoryBatchReserveRequest struct {
+	Items          []model.CheckoutItem `json:"items"`
+	IdempotencyKey string               `json:"idempotency_key"`
+	OrderId        string               `json:"order_id"`
+}
+
+// Outbox events sent to the inventory service
+const (
+	EventPaymentCompleted = "payment.completed" // ship a paid order's reservation
+	EventPaymentReversed  = "payment.reversed"  // release the reservation of a charge that was refunded back
+)
+
+// enqueuePaymentCompleted records, in the payment's own transaction, an outbox event that asks the
+// inventory service to ship the stock reserved for a completed payment. The outbox publisher delivers
+// it after commit and retries on failure; the payment outcome never depends on it.
+func enqueuePaymentCompleted(tx *gorm.DB, payment model.PaymentModel, requestId string) error {
+	baseUrl := inventoryServiceUrl()
+	if baseUrl == "" {
+		log.Warnf("Inventory service URL is not configured; not shipping order %s for payment %d", payment.OrderId, payment.PaymentId)
+		return nil
+	}
+
+	return database.EnqueueEvent(tx, EventPaymentCompleted, strings.TrimRight(baseUrl, "/")+"/v1/inventory/ship", inventoryShipRequest{
+		IdempotencyKey: reservationKey(payment),
+		OrderId:        payment.OrderId,
+	}, requestId, 0)
+}
+
+// reservationKey is the idempotency key the payment's inventory reservation was made under. A charge only
+// shares its own key with the reservation when the caller used one key for both, as checkout does.
+func reservationKey(payment model.PaymentModel) string {
+	if payment.ReservationKey != "" {
+		return payment.ReservationKey
+	}
+	return payment.IdempotencyKey
+}
+
+// postInventory POSTs a JSON body to an inventory service endpoint and returns the response status
+func postInventory(baseUrl string, path string, payload interface{}, requestId string) (int, error) {
+	if baseUrl == "" {
+		return 0, fmt.Errorf("inventory service URL is not configured")
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(baseUrl, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if requestId != "" {
+		req.Header.Set(common.RequestIdHeader, requestId)
+	}
+
+	resp, err := inventoryClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
+
+// inventoryServiceUrl returns the configured inventory service base URL
+func inventoryServiceUrl() string {
+	if config := common.GetConfig(); config != nil {
+		return config.Inventory.Url
+	}
+	return ""
+}
+
+// enqueuePaymentReversed records an outbox event that asks the inventory service to release the stock
+// held for a payment whose charge was given back, e.g. a batch line reversed when a later line failed
+func enqueuePaymentReversed(tx *gorm.DB, payment model.PaymentModel, requestId string) error {
+	baseUrl := inventoryServiceUrl()
+	if baseUrl == "" {
+		log.Warnf("Inventory service URL is not configured; not releasing order %s for payment %d", payment.OrderId, payment.PaymentId)
+		return nil
+	}
+
+	return database.EnqueueEvent(tx, EventPaymentReversed, strings.TrimRight(baseUrl, "/")+"/v1/inventory/release", inventoryShipRequest{
+		IdempotencyKey: reservationKey(payment),
+		OrderId:        payment.OrderId,
+	}, requestId, 0)
+}
@@ -0,0 +1,156 @@
+package payment_service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PoojaSrinivasan18/payment-service/common"
+	"github.com/PoojaSrinivasan18/payment-service/database"
+	"github.com/PoojaSrinivasan18/payment-service/database/dbtest"
+	"github.com/PoojaSrinivasan18/payment-service/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// useConfig swaps the package configuration for the duration of one test
+func useConfig(t *testing.T, config *common.Configuration) {
+	t.Helper()
+	previous := common.Config
+	common.Config = config
+	t.Cleanup(func() { common.Config = previous })
+}
+
+func TestCompletedChargeShipsInventoryThroughOutbox(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := dbtest.Open(t, &model.PaymentModel{}, &database.OutboxEvent{})
+	useGateway(t, &countingGateway{})
+
+	type shipCall struct {
+		path string
+		body inventoryShipRequest
+	}
+	calls := make(chan shipCall, 1)
+	inventory := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body inventoryShipRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode ship request: %v", err)
+		}
+		calls <- shipCall{path: r.URL.Path, body: body}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer inventory.Close()
+
+	useConfig(t, &common.Configuration{
+		Inventory: common.InventoryConfiguration{Url: inventory.URL},
+		Outbox:    common.OutboxConfiguration{Interval: 10 * time.Millisecond},
+	})
+
+	router := gin.New()
+	router.POST("/v1/payments/charge", ChargePayment)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/payments/charge",
+		strings.NewReader(`{"order_id":"ORD-42","amount":25,"method":"CREDIT_CARD","idempotency_key":"ship-key"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("charge: got %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var pending int64
+	db.Model(&database.OutboxEvent{}).Where("event_type = ? AND status = ?", EventPaymentCompleted, database.OutboxPending).Count(&pending)
+	if pending != 1 {
+		t.Fatalf("got %d pending %s events after the charge, want 1", pending, EventPaymentCompleted)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	database.StartOutboxPublisher(ctx, "payment")
+
+	select {
+	case call := <-calls:
+		if call.path != "/v1/inventory/ship" {
+			t.Errorf("got POST %s, want /v1/inventory/ship", call.path)
+		}
+		if call.body.IdempotencyKey != "ship-key" || call.body.OrderId != "ORD-42" {
+			t.Errorf("got ship request %+v, want idempotency_key ship-key and order_id ORD-42", call.body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("inventory service was not called")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var event database.OutboxEvent
+		if err := db.Where("event_type = ?", EventPaymentCompleted).First(&event).Error; err != nil {
+			t.Fatalf("load outbox event: %v", err)
+		}
+		if event.Status == database.OutboxSent {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("outbox event is %s, want %s", event.Status, database.OutboxSent)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Let the publisher see the cancellation before the test database is closed underneath it
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+}
+
+func TestCompletedChargeShipsReservationKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		wantKey string
+	}{
+		{"charge naming its reservation", "/v1/payments/charge",
+			`{"order_id":"ORD-1","amount":25,"idempotency_key":"charge-key","reservation_key":"reserve-key"}`, "reserve-key"},
+		{"charge sharing its key", "/v1/payments/charge",
+			`{"order_id":"ORD-1","amount":25,"idempotency_key":"shared-key"}`, "shared-key"},
+		{"batch line naming its reservation", "/v1/payments/charge/batch",
+			`{"idempotency_key":"batch-key","charges":[{"order_id":"ORD-1","amount":25,"reservation_key":"reserve-key"}]}`, "reserve-key"},
+		{"batch line without a reservation key", "/v1/payments/charge/batch",
+			`{"idempotency_key":"batch-key","charges":[{"order_id":"ORD-1","amount":25}]}`, "batch-key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.Open(t, &model.PaymentModel{}, &database.OutboxEvent{})
+			useGateway(t, &countingGateway{})
+			useConfig(t, &common.Configuration{Inventory: common.InventoryConfiguration{Url: "http://inventory.test"}})
+
+			router := gin.New()
+			router.POST("/v1/payments/charge", ChargePayment)
+			router.POST("/v1/payments/charge/batch", ChargePaymentBatch)
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("got %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+			}
+
+			var event database.OutboxEvent
+			if err := db.Where("event_type = ?", EventPaymentCompleted).First(&event).Error; err != nil {
+				t.Fatalf("load outbox event: %v", err)
+			}
+			var ship inventoryShipRequest
+			if err := json.Unmarshal([]byte(event.Payload), &ship); err != nil {
+				t.Fatalf("decode outbox payload: %v", err)
+			}
+			if ship.IdempotencyKey != tt.wantKey || ship.OrderId != "ORD-1" {
+				t.Fatalf("got ship request %+v, want idempotency_key %s and order_id ORD-1", ship, tt.wantKey)
+			}
+		})
+	}
+}
@@ -1,17 +1,28 @@
 package payment_service
 
 import (
+	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"math/rand"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/PoojaSrinivasan18/payment-service/auth"
+	"github.com/PoojaSrinivasan18/payment-service/common"
 	"github.com/PoojaSrinivasan18/payment-service/database"
 	"github.com/PoojaSrinivasan18/payment-service/model"
 
 	"github.com/apex/log"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func GetPaymentById(c *gin.Context) {
@@ -24,7 +35,7 @@ func GetPaymentById(c *gin.Context) {
 	paymentId, err := strconv.Atoi(paymentIdStr)
 	if err != nil {
 		log.Errorf("Invalid payment ID: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID", "message": "Payment ID must be a valid integer"})
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Payment ID must be a valid integer")
 		return
 	}
 
@@ -34,168 +45,884 @@ func GetPaymentById(c *gin.Context) {
 	t := database.Where("payment_id=?", paymentId).First(&existingPaymentDetail)
 	if t.Error != nil {
 		log.Errorf("DB query error %v", t.Error)
-		c.IndentedJSON(http.StatusNotFound, gin.H{"message": t.Error})
+		if errors.Is(t.Error, gorm.ErrRecordNotFound) {
+			common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "Payment not found")
+			return
+		}
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to fetch payment")
 		return
 	}
 
 	c.IndentedJSON(http.StatusOK, existingPaymentDetail)
 }
+
+// ListPayments returns payments filtered by customer, order, status and creator with pagination
+func ListPayments(c *gin.Context) {
+	var payments []model.PaymentModel
+	db, cancel := database.RequestDB(c)
+	defer cancel()
+
+	query := db.Model(&model.PaymentModel{})
+
+	if customerId := c.Query("customer_id"); customerId != "" {
+		id, err := strconv.Atoi(customerId)
+		if err != nil {
+			common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid customer ID")
+			return
+		}
+		query = query.Where("customer_id = ?", id)
+	}
+	if orderId := c.Query("order_id"); orderId != "" {
+		query = query.Where("order_id = ?", orderId)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", strings.ToUpper(status))
+	}
+	if createdBy := c.Query("created_by"); createdBy != "" {
+		query = query.Where("created_by = ?", createdBy)
+	}
+
+	limit, offset := common.Paginate(c)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		if database.RespondIfTimedOut(c, err) {
+			return
+		}
+		log.Errorf("DB count error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to list payments")
+		return
+	}
+
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&payments).Error; err != nil {
+		if database.RespondIfTimedOut(c, err) {
+			return
+		}
+		log.Errorf("DB query error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to list payments")
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, common.NewPageResponse(payments, total, limit, offset).WithLegacyKey("payments", len(payments)))
+}
+
+// GetPaymentsByOrder returns every charge and refund recorded for an order with its net amount
+func GetPaymentsByOrder(c *gin.Context) {
+	orderId := c.Param("orderId")
+
+	var payments []model.PaymentModel
+	if err := database.GetDB().Where("order_id = ?", orderId).Order("created_at ASC").Find(&payments).Error; err != nil {
+		log.Errorf("DB query error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to fetch payments for order")
+		return
+	}
+
+	if len(payments) == 0 {
+		common.RespondErrorWithDetails(c, http.StatusNotFound, common.CodeNotFound, "No payments found for order", gin.H{"order_id": orderId})
+		return
+	}
+
+	// Refund rows carry negative amounts; only settled charges count toward the net
+	netAmount := 0.0
+	for _, payment := range payments {
+		if payment.Amount < 0 || payment.Status == "COMPLETED" || payment.Status == "PARTIALLY_REFUNDED" || payment.Status == "REFUNDED" {
+			netAmount += payment.Amount
+		}
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"order_id":   orderId,
+		"payments":   payments,
+		"count":      len(payments),
+		"net_amount": roundAmount(netAmount),
+	})
+}
+
+// CustomerPaymentSummary is a customer's lifetime payment totals; refunds are reported as positive amounts
+type CustomerPaymentSummary struct {
+	CustomerId    int        `json:"customer_id"`
+	TotalCharged  float64    `json:"total_charged"`
+	TotalRefunded float64    `json:"total_refunded"`
+	NetSpend      float64    `json:"net_spend"`
+	PaymentCount  int64      `json:"payment_count"`
+	LastPaymentAt *time.Time `json:"last_payment_at"`
+}
+
+// GetCustomerPaymentSummary returns a customer's lifetime charged, refunded and net totals. Only settled
+// charges count, matching GetPaymentsByOrder; a customer with no payments gets zeros rather than a 404.
+func GetCustomerPaymentSummary(c *gin.Context) {
+	customerId, err := strconv.Atoi(c.Param("customerId"))
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid customer ID")
+		return
+	}
+
+	var totals struct {
+		TotalCharged  float64
+		TotalRefunded float64
+		PaymentCount  int64
+		LastPaymentAt *time.Time
+	}
+	if err := database.GetDB().Model(&model.PaymentModel{}).
+		Select(`COALESCE(SUM(CASE WHEN amount > 0 THEN amount END), 0) AS total_charged,
+			COALESCE(-SUM(CASE WHEN amount < 0 THEN amount END), 0) AS total_refunded,
+			COUNT(CASE WHEN amount > 0 THEN 1 END) AS payment_count,
+			MAX(CASE WHEN amount > 0 THEN created_at END) AS last_payment_at`).
+		Where("customer_id = ? AND (amount < 0 OR status IN ?)", customerId, []string{"COMPLETED", "PARTIALLY_REFUNDED", "REFUNDED"}).
+		Scan(&totals).Error; err != nil {
+		log.Errorf("DB customer summary error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to summarize payments")
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, CustomerPaymentSummary{
+		CustomerId:    customerId,
+		TotalCharged:  roundAmount(totals.TotalCharged),
+		TotalRefunded: roundAmount(totals.TotalRefunded),
+		NetSpend:      roundAmount(totals.TotalCharged - totals.TotalRefunded),
+		PaymentCount:  totals.PaymentCount,
+		LastPaymentAt: totals.LastPaymentAt,
+	})
+}
+
+// PaymentReportRow is one day/status bucket of the reconciliation report
+type PaymentReportRow struct {
+	Date        string  `json:"date"`
+	Status      string  `json:"status"`
+	Count       int64   `json:"count"`
+	TotalAmount float64 `json:"total_amount"`
+}
+
+// GetPaymentReport summarizes payments by day and status; refunds count as negative amounts
+func GetPaymentReport(c *gin.Context) {
+	const dateLayout = "2006-01-02"
+
+	to := time.Now()
+	if t := c.Query("to"); t != "" {
+		parsed, err := time.Parse(dateLayout, t)
+		if err != nil {
+			common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Dates must be formatted as YYYY-MM-DD")
+			return
+		}
+		to = parsed
+	}
+
+	// Default to the last 7 days ending at the to date
+	from := to.AddDate(0, 0, -6)
+	if f := c.Query("from"); f != "" {
+		parsed, err := time.Parse(dateLayout, f)
+		if err != nil {
+			common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Dates must be formatted as YYYY-MM-DD")
+			return
+		}
+		from = parsed
+	}
+
+	// Both bounds are inclusive whole days
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	end := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+
+	if !start.Before(end) {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "from date must not be after to date")
+		return
+	}
+	if end.Sub(start) > 90*24*time.Hour {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Report range cannot exceed 90 days")
+		return
+	}
+
+	var rows []PaymentReportRow
+	err := database.GetDB().Model(&model.PaymentModel{}).
+		Select("TO_CHAR(DATE(created_at), 'YYYY-MM-DD') AS date, status, COUNT(*) AS count, COALESCE(SUM(amount), 0) AS total_amount").
+		Where("created_at >= ? AND created_at < ?", start, end).
+		Group("DATE(created_at), status").
+		Order("DATE(created_at), status").
+		Scan(&rows).Error
+	if err != nil {
+		log.Errorf("DB query error %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to build payment report")
+		return
+	}
+
+	for i := range rows {
+		rows[i].TotalAmount = roundAmount(rows[i].TotalAmount)
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"from":   start.Format(dateLayout),
+		"to":     end.AddDate(0, 0, -1).Format(dateLayout),
+		"report": rows,
+	})
+}
+
 func MakePayment(c *gin.Context) {
 	var paymentModel model.PaymentModel
 	err := c.ShouldBind(&paymentModel)
 	if err != nil {
 		log.Errorf("FORM binding error %v", err.Error())
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err})
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, err.Error())
 		return
 	}
 
 	tx := database.GetDB().Create(&paymentModel)
 	if tx.Error != nil {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Error making payment"})
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Error making payment")
 		return
 	}
 
 	c.IndentedJSON(http.StatusOK, paymentModel)
 }
 
+// SeedPayments replaces the payments table with historical rows from the seed CSV
+func SeedPayments(c *gin.Context) {
+	log.Infof("Started cleaning up existing payment data")
+
+	db := database.GetDB()
+	if del := db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&model.PaymentModel{}); del.Error != nil {
+		log.Errorf("DB delete error: %v", del.Error)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Error clearing payments table")
+		return
+	}
+
+	log.Infof("Cleared existing payment data")
+
+	csvPath := filepath.Join("seeddata", "eci_payments.csv")
+	f, err := os.Open(csvPath)
+	if err != nil {
+		log.Errorf("Cannot open seed file: %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Cannot open seed file")
+		return
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		log.Errorf("CSV read error: %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "CSV read error")
+		return
+	}
+	if len(records) < 2 {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "CSV contains no data")
+		return
+	}
+
+	header := records[0]
+	idx := make(map[string]int)
+	for i, h := range header {
+		idx[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+
+	// field returns the trimmed value of a column, or "" when the column is missing
+	field := func(row []string, name string) string {
+		if v, ok := idx[name]; ok && v < len(row) {
+			return strings.TrimSpace(row[v])
+		}
+		return ""
+	}
+
+	inserted := 0
+
+	for ri := 1; ri < len(records); ri++ {
+		row := records[ri]
+		m := model.PaymentModel{
+			OrderId:        field(row, "order_id"),
+			Currency:       DefaultCurrency,
+			Method:         field(row, "method"),
+			Status:         strings.ToUpper(field(row, "status")),
+			Reference:      field(row, "reference"),
+			IdempotencyKey: field(row, "idempotency_key"),
+			CreatedBy:      common.SystemActor(),
+		}
+
+		if s := field(row, "amount"); s != "" {
+			if amount, e := strconv.ParseFloat(s, 64); e == nil {
+				m.Amount = roundAmount(amount)
+			}
+		}
+
+		if s := field(row, "customer_id"); s != "" {
+			if id, e := strconv.Atoi(s); e == nil {
+				m.CustomerId = id
+			}
+		}
+
+		if m.Method == "" {
+			m.Method = "CREDIT_CARD"
+		}
+		if m.Status == "" {
+			m.Status = "COMPLETED"
+		}
+		if m.Reference == "" {
+			m.Reference = generatePaymentReference()
+		}
+		if m.IdempotencyKey == "" {
+			m.IdempotencyKey = fmt.Sprintf("seed_%s_%d", m.Reference, ri)
+		}
+
+		// Fully refunded history keeps refunded_amount consistent with the status
+		if m.Status == "REFUNDED" && m.Amount > 0 {
+			m.RefundedAmount = m.Amount
+		}
+
+		// Rows without a created_at are stamped by GORM on insert
+		if s := field(row, "created_at"); s != "" {
+			if parsed, e := time.Parse(time.RFC3339, s); e == nil {
+				m.CreatedAt = parsed
+			}
+		}
+		m.UpdatedAt = m.CreatedAt
+
+		tx := db.Create(&m)
+		if tx.Error != nil {
+			log.Errorf("DB insert error at CSV row %d: %v", ri+1, tx.Error)
+			continue
+		}
+		inserted++
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"inserted": inserted})
+}
+
 func ChargePayment(c *gin.Context) {
 	var req model.ChargeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		log.Errorf("JSON binding error: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid request", err.Error())
+		return
+	}
+
+	req.IdempotencyKey = common.ResolveIdempotencyKey(c, req.IdempotencyKey)
+	if req.IdempotencyKey == "" {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "idempotency_key or an Idempotency-Key header is required")
+		return
+	}
+
+	currency, ok := normalizeCurrency(req.Currency)
+	if !ok {
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Unsupported currency", gin.H{"currency": req.Currency})
 		return
 	}
 
 	db := database.GetDB()
 
 	// Check for existing payment with same idempotency key
-	var existingPayment model.PaymentModel
-	if err := db.Where("idempotency_key = ?", req.IdempotencyKey).First(&existingPayment).Error; err == nil {
-		// Return existing payment
-		c.JSON(http.StatusOK, gin.H{
-			"message":    "Payment already processed",
-			"payment":    existingPayment,
-			"idempotent": true,
-		})
+	if respondWithExistingPayment(c, db, req.IdempotencyKey) {
 		return
 	}
 
 	// Process new payment
+	requestId := common.RequestId(c)
+	payment, err := chargeNewPayment(db, gatewayFor(c), newPayment(req, currency, auth.Actor(c)), func(tx *gorm.DB, payment model.PaymentModel) error {
+		return enqueuePaymentCompleted(tx, payment, requestId)
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) && respondWithExistingPayment(c, db, req.IdempotencyKey) {
+			return
+		}
+		log.Errorf("Failed to save payment: %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Payment processing failed")
+		return
+	}
+
+	if payment.Status == "COMPLETED" {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Payment processed successfully",
+			"payment": payment,
+		})
+	} else {
+		common.RespondErrorWithDetails(c, http.StatusPaymentRequired, common.CodePaymentFailed, "Payment failed", gin.H{
+			"failure_reason": payment.FailureReason,
+			"payment":        payment,
+		})
+	}
+}
+
+// newPayment builds the PROCESSING payment a charge request records before the gateway is called
+func newPayment(req model.ChargeRequest, currency string, createdBy string) model.PaymentModel {
 	payment := model.PaymentModel{
 		OrderId:        req.OrderId,
 		Amount:         req.Amount,
+		Currency:       currency,
 		CustomerId:     req.CustomerId,
 		Method:         req.Method,
 		Status:         "PROCESSING",
 		IdempotencyKey: req.IdempotencyKey,
+		ReservationKey: req.ReservationKey,
 		Reference:      generatePaymentReference(),
-		CreatedAt:      time.Now(),
-		UpdatedAt:      time.Now(),
+		CreatedBy:      createdBy,
 	}
 
 	// Default method if not specified
 	if payment.Method == "" {
 		payment.Method = "CREDIT_CARD"
 	}
+	return payment
+}
 
-	// Simulate payment processing (replace with actual payment gateway)
-	success := simulatePaymentProcessing(payment.Amount, payment.Method)
+// chargeNewPayment records a PROCESSING payment built by newPayment, charges the gateway and settles the
+// payment as COMPLETED or FAILED. The PROCESSING row is committed before the gateway call, so a crash
+// mid-charge leaves it for the sweeper. A concurrent retry with the same idempotency key loses on the
+// unique index and gets gorm.ErrDuplicatedKey before anything is charged.
+func chargeNewPayment(db *gorm.DB, gateway PaymentGateway, payment model.PaymentModel, onCompleted func(tx *gorm.DB, payment model.PaymentModel) error) (model.PaymentModel, error) {
+	payment, outcome, err := startCharge(db, gateway, payment)
+	if err != nil {
+		return model.PaymentModel{}, err
+	}
+
+	// onCompleted runs in the same transaction as the status change, so its outbox events commit with it
+	return settlePayment(db, payment.PaymentId, outcome.apply, func(tx *gorm.DB, payment model.PaymentModel) error {
+		if onCompleted != nil && payment.Status == "COMPLETED" {
+			return onCompleted(tx, payment)
+		}
+		return nil
+	})
+}
+
+// chargeOutcome is what the gateway answered to a charge, kept until the payment is settled
+type chargeOutcome struct {
+	result GatewayResult
+	err    error
+}
 
-	if success {
+// succeeded reports whether the gateway took the money
+func (o chargeOutcome) succeeded() bool {
+	return o.err == nil && o.result.Success
+}
+
+// apply records the outcome on a PROCESSING payment as COMPLETED or FAILED
+func (o chargeOutcome) apply(payment *model.PaymentModel) {
+	payment.GatewayTransactionId = o.result.TransactionId
+	if o.succeeded() {
 		payment.Status = "COMPLETED"
 	} else {
 		payment.Status = "FAILED"
+		payment.FailureReason = failureReason(o.result, o.err)
+	}
+}
+
+// startCharge commits the PROCESSING payment and charges the gateway, leaving the payment to be settled
+// with the returned outcome. Batch charges settle their lines together once every line is charged.
+func startCharge(db *gorm.DB, gateway PaymentGateway, payment model.PaymentModel) (model.PaymentModel, chargeOutcome, error) {
+	if err := db.Create(&payment).Error; err != nil {
+		return model.PaymentModel{}, chargeOutcome{}, err
+	}
+
+	// Process the charge through the configured payment gateway
+	result, err := gateway.Charge(payment.Amount, payment.Method, payment.Reference)
+	if err != nil {
+		log.Errorf("Gateway charge error: %v", err)
+	}
+	return payment, chargeOutcome{result: result, err: err}, nil
+}
+
+// settlePayment applies a gateway outcome to a PROCESSING payment under a row lock, then runs onSettled
+// in the same transaction. A payment the sweeper timed out is settled too; one a webhook already moved on
+// is returned unchanged.
+func settlePayment(db *gorm.DB, paymentId int, settle func(payment *model.PaymentModel), onSettled func(tx *gorm.DB, payment model.PaymentModel) error) (model.PaymentModel, error) {
+	var payment model.PaymentModel
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, paymentId).Error; err != nil {
+			return err
+		}
+
+		// The sweeper only guessed that a slow charge failed, so the gateway's late answer replaces its timeout
+		if payment.Status == "FAILED" && payment.FailureReason == FailureTimeout {
+			log.Warnf("Payment %d was timed out by the sweeper before the gateway answered; settling it with the gateway's outcome", payment.PaymentId)
+			payment.FailureReason = ""
+		} else if payment.Status != "PROCESSING" {
+			log.Warnf("Payment %d was settled as %s while the gateway call was in flight; keeping it", payment.PaymentId, payment.Status)
+			return nil
+		}
+
+		settle(&payment)
+		if err := tx.Save(&payment).Error; err != nil {
+			return err
+		}
+		if onSettled != nil {
+			return onSettled(tx, payment)
+		}
+		return nil
+	})
+	if err != nil {
+		return model.PaymentModel{}, err
+	}
+	return payment, nil
+}
+
+// AuthorizePayment places a hold on the funds without capturing them
+func AuthorizePayment(c *gin.Context) {
+	var req model.ChargeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Errorf("JSON binding error: %v", err)
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid request", err.Error())
+		return
+	}
+
+	req.IdempotencyKey = common.ResolveIdempotencyKey(c, req.IdempotencyKey)
+	if req.IdempotencyKey == "" {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "idempotency_key or an Idempotency-Key header is required")
+		return
+	}
+
+	currency, ok := normalizeCurrency(req.Currency)
+	if !ok {
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Unsupported currency", gin.H{"currency": req.Currency})
+		return
+	}
+
+	db := database.GetDB()
+
+	// Check for existing payment with same idempotency key
+	if respondWithExistingPayment(c, db, req.IdempotencyKey) {
+		return
 	}
 
-	payment.UpdatedAt = time.Now()
+	payment := model.PaymentModel{
+		OrderId:          req.OrderId,
+		Amount:           req.Amount,
+		AuthorizedAmount: req.Amount,
+		Currency:         currency,
+		CustomerId:       req.CustomerId,
+		Method:           req.Method,
+		Status:           "PROCESSING",
+		IdempotencyKey:   req.IdempotencyKey,
+		Reference:        generatePaymentReference(),
+		CreatedBy:        auth.Actor(c),
+	}
 
-	// Save payment record
+	// Default method if not specified
+	if payment.Method == "" {
+		payment.Method = "CREDIT_CARD"
+	}
+
+	// Record the attempt first; a concurrent retry with the same key loses on the unique index
 	if err := db.Create(&payment).Error; err != nil {
-		log.Errorf("Failed to save payment: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Payment processing failed"})
+		if errors.Is(err, gorm.ErrDuplicatedKey) && respondWithExistingPayment(c, db, req.IdempotencyKey) {
+			return
+		}
+		log.Errorf("Failed to save authorization: %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Payment authorization failed")
 		return
 	}
 
-	if payment.Status == "COMPLETED" {
+	// Authorize through the configured payment gateway
+	result, err := gatewayFor(c).Authorize(payment.Amount, payment.Method, payment.Reference)
+	if err != nil {
+		log.Errorf("Gateway authorize error: %v", err)
+	}
+
+	payment, err = settlePayment(db, payment.PaymentId, func(payment *model.PaymentModel) {
+		payment.GatewayTransactionId = result.TransactionId
+		if err == nil && result.Success {
+			now := time.Now()
+			payment.Status = "AUTHORIZED"
+			payment.AuthorizedAt = &now
+		} else {
+			payment.Status = "FAILED"
+			payment.FailureReason = failureReason(result, err)
+		}
+	}, nil)
+	if err != nil {
+		log.Errorf("Failed to save authorization: %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Payment authorization failed")
+		return
+	}
+
+	if payment.Status == "AUTHORIZED" {
 		c.JSON(http.StatusOK, gin.H{
-			"message": "Payment processed successfully",
+			"message": "Payment authorized successfully",
 			"payment": payment,
 		})
 	} else {
-		c.JSON(http.StatusPaymentRequired, gin.H{
-			"error":   "Payment failed",
-			"payment": payment,
+		common.RespondErrorWithDetails(c, http.StatusPaymentRequired, common.CodePaymentFailed, "Payment authorization failed", gin.H{
+			"failure_reason": payment.FailureReason,
+			"payment":        payment,
 		})
 	}
 }
 
+// CapturePayment captures all or part of a previously authorized payment
+func CapturePayment(c *gin.Context) {
+	paymentIdStr := c.Param("id")
+	paymentId, err := strconv.Atoi(paymentIdStr)
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid payment ID")
+		return
+	}
+
+	// The body is optional; an empty one captures the full authorization
+	var req model.CaptureRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		log.Errorf("JSON binding error: %v", err)
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid request", err.Error())
+		return
+	}
+
+	db := database.GetDB()
+
+	// Lock the payment for the whole capture, so a concurrent capture waits and then sees it is no longer authorized
+	tx := db.Begin()
+
+	var payment model.PaymentModel
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, paymentId).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "Payment not found")
+			return
+		}
+		log.Errorf("Failed to lock payment %d for capture: %v", paymentId, err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Payment capture failed")
+		return
+	}
+
+	if payment.Status != "AUTHORIZED" {
+		tx.Rollback()
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Only authorized payments can be captured", gin.H{"status": payment.Status})
+		return
+	}
+
+	// Capture the full authorization unless a partial amount is given
+	captureAmount := roundAmount(req.Amount)
+	if captureAmount <= 0 {
+		captureAmount = payment.AuthorizedAmount
+	}
+
+	if captureAmount > payment.AuthorizedAmount {
+		tx.Rollback()
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Capture amount exceeds authorized amount", gin.H{
+			"authorized_amount": payment.AuthorizedAmount,
+		})
+		return
+	}
+
+	payment.Amount = captureAmount
+	payment.Status = "COMPLETED"
+	if err := tx.Save(&payment).Error; err != nil {
+		tx.Rollback()
+		log.Errorf("Failed to capture payment: %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Payment capture failed")
+		return
+	}
+	if err := enqueuePaymentCompleted(tx, payment, common.RequestId(c)); err != nil {
+		tx.Rollback()
+		log.Errorf("Failed to capture payment: %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Payment capture failed")
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		log.Errorf("Failed to commit payment capture: %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Payment capture failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Payment captured successfully",
+		"payment": payment,
+	})
+}
+
 func RefundPayment(c *gin.Context) {
 	paymentIdStr := c.Param("id")
 	paymentId, err := strconv.Atoi(paymentIdStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid payment ID")
 		return
 	}
 
 	var req model.RefundRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		log.Errorf("JSON binding error: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid request", err.Error())
 		return
 	}
+	req.IdempotencyKey = common.ResolveIdempotencyKey(c, req.IdempotencyKey)
 
 	db := database.GetDB()
 
-	// Find original payment
+	// Check for existing refund with same idempotency key
+	if req.IdempotencyKey != "" && respondWithExistingRefund(c, db, req.IdempotencyKey) {
+		return
+	}
+
+	// Lock the original payment for the whole refund, so concurrent refunds check the remaining
+	// balance and call the gateway one at a time
+	tx := db.Begin()
+
 	var payment model.PaymentModel
-	if err := db.First(&payment, paymentId).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, paymentId).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "Payment not found")
+			return
+		}
+		log.Errorf("Failed to lock payment %d for refund: %v", paymentId, err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Refund processing failed")
+		return
+	}
+
+	// A retry that waited on the lock finds the refund its twin just recorded
+	if req.IdempotencyKey != "" && respondWithExistingRefund(c, tx, req.IdempotencyKey) {
+		tx.Rollback()
+		return
+	}
+
+	if payment.Status == "AUTHORIZED" {
+		tx.Rollback()
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Cannot refund an authorized payment before it is captured")
+		return
+	}
+
+	if payment.Status != "COMPLETED" && payment.Status != "PARTIALLY_REFUNDED" {
+		tx.Rollback()
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Cannot refund non-completed payment")
 		return
 	}
 
-	if payment.Status != "COMPLETED" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot refund non-completed payment"})
+	// Refunds are always issued in the currency of the original payment
+	if req.Currency != "" && !strings.EqualFold(req.Currency, payment.Currency) {
+		tx.Rollback()
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Refund currency must match the original payment", gin.H{
+			"payment_currency": payment.Currency,
+		})
 		return
 	}
 
-	// Calculate refund amount
-	refundAmount := req.Amount
-	if refundAmount <= 0 || refundAmount > payment.Amount {
-		refundAmount = payment.Amount
+	// Calculate refund amount against what is still refundable
+	remaining := roundAmount(payment.Amount - payment.RefundedAmount)
+	refundAmount := roundAmount(req.Amount)
+	if refundAmount <= 0 {
+		refundAmount = remaining
+	}
+
+	if refundAmount > remaining {
+		tx.Rollback()
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Refund amount exceeds remaining refundable balance", gin.H{
+			"already_refunded":     payment.RefundedAmount,
+			"remaining_refundable": remaining,
+		})
+		return
+	}
+
+	// Fall back to a generated key when the client does not supply one
+	refundKey := req.IdempotencyKey
+	if refundKey == "" {
+		refundKey = payment.IdempotencyKey + "_refund_" + strconv.FormatInt(time.Now().Unix(), 10)
+	}
+
+	refundReference := generateRefundReference(payment.Reference)
+
+	// Return the funds through the configured payment gateway
+	result, err := gatewayFor(c).Refund(refundAmount, payment.Method, refundReference)
+	if err != nil || !result.Success {
+		tx.Rollback()
+		log.Errorf("Gateway refund failed for payment %d: %v", payment.PaymentId, err)
+		common.RespondErrorWithDetails(c, http.StatusBadGateway, common.CodeUpstream, "Refund declined by payment gateway", gin.H{"failure_reason": failureReason(result, err)})
+		return
 	}
 
 	// Create refund record
 	refund := model.PaymentModel{
-		OrderId:        payment.OrderId,
-		Amount:         -refundAmount, // Negative amount for refund
-		CustomerId:     payment.CustomerId,
-		Method:         payment.Method,
-		Status:         "REFUNDED",
-		Reference:      generateRefundReference(payment.Reference),
-		IdempotencyKey: payment.IdempotencyKey + "_refund_" + strconv.FormatInt(time.Now().Unix(), 10),
-		CreatedAt:      time.Now(),
-		UpdatedAt:      time.Now(),
+		OrderId:              payment.OrderId,
+		Amount:               -refundAmount, // Negative amount for refund
+		Currency:             payment.Currency,
+		CustomerId:           payment.CustomerId,
+		Method:               payment.Method,
+		Status:               "REFUNDED",
+		Reference:            refundReference,
+		IdempotencyKey:       refundKey,
+		GatewayTransactionId: result.TransactionId,
+		OriginalPaymentId:    &payment.PaymentId,
+		CreatedBy:            auth.Actor(c),
 	}
 
 	// Save refund record
-	if err := db.Create(&refund).Error; err != nil {
-		log.Errorf("Failed to save refund: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Refund processing failed"})
+	if err := tx.Create(&refund).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrDuplicatedKey) && req.IdempotencyKey != "" && respondWithExistingRefund(c, db, req.IdempotencyKey) {
+			return
+		}
+		log.Errorf("Failed to save refund for payment %d after the gateway refunded it: %v", payment.PaymentId, err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Refund processing failed")
 		return
 	}
 
-	// Update original payment status if full refund
-	if refundAmount == payment.Amount {
+	// Recompute the cumulative refund from every refund record, then set the status from it
+	refunded, err := refundedTotal(tx, payment)
+	if err != nil {
+		tx.Rollback()
+		log.Errorf("Failed to total refunds for payment %d: %v", payment.PaymentId, err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Refund processing failed")
+		return
+	}
+	payment.RefundedAmount = refunded
+	if payment.RefundedAmount >= payment.Amount {
 		payment.Status = "REFUNDED"
-		payment.UpdatedAt = time.Now()
-		db.Save(&payment)
+	} else if payment.RefundedAmount > 0 {
+		payment.Status = "PARTIALLY_REFUNDED"
+	}
+	if err := tx.Save(&payment).Error; err != nil {
+		tx.Rollback()
+		log.Errorf("Failed to update payment after refund: %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Refund processing failed")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Errorf("Failed to commit refund for payment %d: %v", payment.PaymentId, err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Refund processing failed")
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":          "Refund processed successfully",
 		"refund":           refund,
 		"original_payment": payment,
+		"refunded_amount":  payment.RefundedAmount,
+		"remaining":        roundAmount(payment.Amount - payment.RefundedAmount),
+	})
+}
+
+// respondWithExistingPayment replies with the payment stored under the idempotency key, if any
+func respondWithExistingPayment(c *gin.Context, db *gorm.DB, idempotencyKey string) bool {
+	var existingPayment model.PaymentModel
+	if err := db.Where("idempotency_key = ?", idempotencyKey).First(&existingPayment).Error; err != nil {
+		return false
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Payment already processed",
+		"payment":    existingPayment,
+		"idempotent": true,
+	})
+	return true
+}
+
+// respondWithExistingRefund replies with the refund stored under the idempotency key, if any
+func respondWithExistingRefund(c *gin.Context, db *gorm.DB, idempotencyKey string) bool {
+	var existingRefund model.PaymentModel
+	if err := db.Where("idempotency_key = ? AND amount < 0", idempotencyKey).First(&existingRefund).Error; err != nil {
+		return false
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Refund already processed",
+		"refund":     existingRefund,
+		"idempotent": true,
 	})
+	return true
+}
+
+// failureReason picks the reason to record for a declined or errored gateway call
+func failureReason(result GatewayResult, err error) string {
+	if result.FailureReason != "" {
+		return result.FailureReason
+	}
+	if err != nil {
+		return FailureGatewayError
+	}
+	return FailureCardDeclined
 }
 
 // generatePaymentReference creates a unique payment reference
@@ -203,26 +930,38 @@ func generatePaymentReference() string {
 	return fmt.Sprintf("PAY_%d_%d", time.Now().Unix(), rand.Intn(10000))
 }
 
-// generateRefundReference creates a refund reference based on original payment
+// generateRefundReference creates a refund reference based on original payment. The random suffix keeps
+// it unique when several refunds of one payment are issued in the same second.
 func generateRefundReference(originalRef string) string {
-	return fmt.Sprintf("REF_%s_%d", originalRef, time.Now().Unix())
+	return fmt.Sprintf("REF_%s_%016x", originalRef, rand.Uint64())
 }
 
-// simulatePaymentProcessing simulates payment gateway processing
-func simulatePaymentProcessing(amount float64, method string) bool {
-	// Simulate different scenarios based on amount
-	if amount <= 0 {
-		return false
-	}
+// refundedTotal sums the refund records issued against a payment
+func refundedTotal(tx *gorm.DB, payment model.PaymentModel) (float64, error) {
+	var total float64
+	err := tx.Model(&model.PaymentModel{}).
+		Where("original_payment_id = ? AND amount < 0", payment.PaymentId).
+		Select("COALESCE(SUM(-amount), 0)").
+		Scan(&total).Error
+	return roundAmount(total), err
+}
 
-	// Simulate 95% success rate
-	return rand.Float64() < 0.95
+// roundAmount rounds a monetary amount to two decimal places
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
 }
+
 func DeletePayment(c *gin.Context) {
-	paymentId, err := strconv.Atoi(c.Query("paymentId"))
+	// Try to get ID from URL parameter first, then query parameter
+	paymentIdStr := c.Param("id")
+	if paymentIdStr == "" {
+		paymentIdStr = c.Query("paymentId")
+	}
+
+	paymentId, err := strconv.Atoi(paymentIdStr)
 	if err != nil {
 		log.Errorf("Invalid payment ID: %v", err)
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Invalid payment ID"})
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid payment ID")
 		return
 	}
 
@@ -232,13 +971,17 @@ func DeletePayment(c *gin.Context) {
 	t := database.Where("payment_id=?", paymentId).First(&existingPaymentDetail)
 	if t.Error != nil {
 		log.Errorf("DB query error %v", t.Error)
-		c.IndentedJSON(http.StatusNotFound, gin.H{"message": t.Error})
+		if errors.Is(t.Error, gorm.ErrRecordNotFound) {
+			common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "Payment not found")
+			return
+		}
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to fetch payment")
 		return
 	}
 
 	tx := database.Model(&existingPaymentDetail).Delete(existingPaymentDetail)
 	if tx.Error != nil {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Error saving payment data"})
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Error saving payment data")
 		return
 	}
 
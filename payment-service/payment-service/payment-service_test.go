@@ -0,0 +1,147 @@
+package payment_service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PoojaSrinivasan18/payment-service/database"
+	"github.com/PoojaSrinivasan18/payment-service/database/dbtest"
+	"github.com/PoojaSrinivasan18/payment-service/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// useGateway swaps the package gateway for the duration of one test
+func useGateway(t *testing.T, gateway PaymentGateway) {
+	t.Helper()
+	previous := Gateway
+	Gateway = gateway
+	t.Cleanup(func() { Gateway = previous })
+}
+
+// countingGateway approves every request after a short delay, except charges of declineAmount, and
+// counts the charges and refunds it receives
+type countingGateway struct {
+	declineAmount float64
+	charges       atomic.Int32
+	refunds       atomic.Int32
+}
+
+func (g *countingGateway) Charge(amount float64, method string, ref string) (GatewayResult, error) {
+	g.charges.Add(1)
+	time.Sleep(20 * time.Millisecond)
+	if g.declineAmount != 0 && amount == g.declineAmount {
+		return GatewayResult{Success: false, FailureReason: FailureCardDeclined}, nil
+	}
+	return GatewayResult{Success: true, TransactionId: "TXN_" + ref}, nil
+}
+
+func (g *countingGateway) Authorize(amount float64, method string, ref string) (GatewayResult, error) {
+	return GatewayResult{Success: true, TransactionId: "AUTH_" + ref}, nil
+}
+
+func (g *countingGateway) Refund(amount float64, method string, ref string) (GatewayResult, error) {
+	g.refunds.Add(1)
+	return GatewayResult{Success: true, TransactionId: "RFD_" + ref}, nil
+}
+
+func TestChargePaymentConcurrentSameIdempotencyKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := dbtest.Open(t, &model.PaymentModel{}, &database.OutboxEvent{})
+	gateway := &countingGateway{}
+	useGateway(t, gateway)
+
+	// SQLite allows one writer at a time; a single connection makes the concurrent handlers queue instead of failing
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("test database handle: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	router := gin.New()
+	router.POST("/v1/payments/charge", ChargePayment)
+
+	const requests = 2
+	body := `{"order_id":"ORD-1","amount":25,"method":"CREDIT_CARD","idempotency_key":"same-key"}`
+	codes := make([]int, requests)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			req := httptest.NewRequest(http.MethodPost, "/v1/payments/charge", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			codes[i] = w.Code
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("request %d: got %d, want %d", i, code, http.StatusOK)
+		}
+	}
+
+	var count int64
+	db.Model(&model.PaymentModel{}).Where("idempotency_key = ?", "same-key").Count(&count)
+	if count != 1 {
+		t.Fatalf("got %d payments for the idempotency key, want 1", count)
+	}
+	if charges := gateway.charges.Load(); charges != 1 {
+		t.Fatalf("gateway charged %d times, want 1", charges)
+	}
+}
+
+func TestCapturePayment(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := dbtest.Open(t, &model.PaymentModel{}, &database.OutboxEvent{})
+
+	router := gin.New()
+	router.POST("/v1/payments/:id/capture", CapturePayment)
+
+	payment := model.PaymentModel{OrderId: "ORD-1", AuthorizedAmount: 40, Method: "CREDIT_CARD", Status: "AUTHORIZED", IdempotencyKey: "auth-key"}
+	if err := db.Create(&payment).Error; err != nil {
+		t.Fatalf("create payment: %v", err)
+	}
+	path := "/v1/payments/" + strconv.Itoa(payment.PaymentId) + "/capture"
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{"more than authorized", path, `{"amount":50}`, http.StatusBadRequest},
+		{"partial capture", path, `{"amount":30}`, http.StatusOK},
+		{"already captured", path, `{}`, http.StatusBadRequest},
+		{"missing payment", "/v1/payments/999/capture", `{}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Fatalf("%s: got %d, want %d: %s", tt.name, w.Code, tt.status, w.Body.String())
+		}
+	}
+
+	var stored model.PaymentModel
+	if err := db.First(&stored, payment.PaymentId).Error; err != nil {
+		t.Fatalf("load payment: %v", err)
+	}
+	if stored.Status != "COMPLETED" || stored.Amount != 30 {
+		t.Fatalf("got status %s amount %v, want COMPLETED 30", stored.Status, stored.Amount)
+	}
+}
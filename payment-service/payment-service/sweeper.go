@@ -0,0 +1,102 @@
+package payment_service
+
+import (
+	"context"
+	"time"
+
+	"github.com/PoojaSrinivasan18/payment-service/common"
+	"github.com/PoojaSrinivasan18/payment-service/database"
+	"github.com/PoojaSrinivasan18/payment-service/model"
+
+	"github.com/apex/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FailureTimeout is recorded on payments the sweeper gives up on
+const FailureTimeout = "timeout"
+
+const (
+	defaultSweepInterval = 1 * time.Minute
+	defaultSweepMaxAge   = 15 * time.Minute
+)
+
+// ExpireStuckPayments is a background job that fails payments left in PROCESSING. A charge that was only
+// slow is settled again when the gateway answers. The job runs until ctx is cancelled.
+func ExpireStuckPayments(ctx context.Context, interval time.Duration, maxAge time.Duration) {
+	log.Infof("Starting stuck payment sweeper (interval %s, max age %s)", interval, maxAge)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		expireStuckPaymentsOnce(maxAge)
+
+		// Wait for the next sweep or shutdown
+		select {
+		case <-ctx.Done():
+			log.Info("Stuck payment sweeper stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// expireStuckPaymentsOnce fails every payment that has been PROCESSING for longer than maxAge
+func expireStuckPaymentsOnce(maxAge time.Duration) {
+	db := database.GetDB()
+
+	var stuckPayments []model.PaymentModel
+	if err := db.Where("status = ? AND updated_at < ?", "PROCESSING", time.Now().Add(-maxAge)).Find(&stuckPayments).Error; err != nil {
+		log.Errorf("Error finding stuck payments: %v", err)
+		return
+	}
+
+	for _, stuck := range stuckPayments {
+		if err := expirePayment(db, stuck.PaymentId); err != nil {
+			log.Errorf("Failed to expire payment %d: %v", stuck.PaymentId, err)
+		}
+	}
+}
+
+// expirePayment marks a payment FAILED if it is still PROCESSING once locked
+func expirePayment(db *gorm.DB, paymentId int) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var payment model.PaymentModel
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, paymentId).Error; err != nil {
+			return err
+		}
+
+		// The payment may have completed since it was picked up
+		if payment.Status != "PROCESSING" {
+			return nil
+		}
+
+		payment.Status = "FAILED"
+		payment.FailureReason = FailureTimeout
+
+		if err := tx.Save(&payment).Error; err != nil {
+			return err
+		}
+
+		log.Infof("Expired stuck payment %d: PROCESSING -> FAILED (%s)", payment.PaymentId, FailureTimeout)
+		return nil
+	})
+}
+
+// StartSweeperJob starts the stuck payment sweeper using the configured interval and age threshold.
+// The sweeper runs until ctx is cancelled.
+func StartSweeperJob(ctx context.Context) {
+	interval, maxAge := defaultSweepInterval, defaultSweepMaxAge
+	if config := common.GetConfig(); config != nil {
+		if config.Sweeper.Interval > 0 {
+			interval = config.Sweeper.Interval
+		}
+		if config.Sweeper.MaxAge > 0 {
+			maxAge = config.Sweeper.MaxAge
+		}
+	}
+
+	go ExpireStuckPayments(ctx, interval, maxAge)
+	log.Info("Stuck payment sweeper started")
+}
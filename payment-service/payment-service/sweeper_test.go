@@ -0,0 +1,43 @@
+package payment_service
+
+import (
+	"testing"
+
+	"github.com/PoojaSrinivasan18/payment-service/database"
+	"github.com/PoojaSrinivasan18/payment-service/database/dbtest"
+	"github.com/PoojaSrinivasan18/payment-service/model"
+)
+
+func TestSettleAfterSweeperTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		outcome     chargeOutcome
+		wantStatus  string
+		wantFailure string
+	}{
+		{"late success", chargeOutcome{result: GatewayResult{Success: true, TransactionId: "TXN_1"}}, "COMPLETED", ""},
+		{"late decline", chargeOutcome{result: GatewayResult{FailureReason: FailureCardDeclined}}, "FAILED", FailureCardDeclined},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.Open(t, &model.PaymentModel{}, &database.OutboxEvent{})
+			payment := model.PaymentModel{OrderId: "ORD-1", Amount: 25, Method: "CREDIT_CARD", Status: "PROCESSING", IdempotencyKey: "slow"}
+			if err := db.Create(&payment).Error; err != nil {
+				t.Fatalf("create payment: %v", err)
+			}
+
+			// The sweeper gives up on the charge while the gateway is still answering
+			if err := expirePayment(db, payment.PaymentId); err != nil {
+				t.Fatalf("expire payment: %v", err)
+			}
+
+			settled, err := settlePayment(db, payment.PaymentId, tt.outcome.apply, nil)
+			if err != nil {
+				t.Fatalf("settle payment: %v", err)
+			}
+			if settled.Status != tt.wantStatus || settled.FailureReason != tt.wantFailure {
+				t.Fatalf("got %s (%q), want %s (%q)", settled.Status, settled.FailureReason, tt.wantStatus, tt.wantFailure)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package payment_service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/PoojaSrinivasan18/payment-service/common"
+	"github.com/PoojaSrinivasan18/payment-service/database"
+	"github.com/PoojaSrinivasan18/payment-service/model"
+
+	"github.com/apex/log"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WebhookSignatureHeader carries the hex HMAC-SHA256 of the raw request body, optionally prefixed with "sha256="
+const WebhookSignatureHeader = "X-Signature"
+
+// PaymentWebhook applies an asynchronous gateway callback to a PROCESSING payment. Callbacks must be signed
+// with the shared webhook secret; duplicates for a payment already in the reported state are acknowledged.
+func PaymentWebhook(c *gin.Context) {
+	secret := ""
+	if config := common.GetConfig(); config != nil {
+		secret = config.Webhook.Secret
+	}
+	if secret == "" {
+		log.Error("Rejecting payment webhook: no webhook secret is configured")
+		common.RespondError(c, http.StatusServiceUnavailable, common.CodeInternal, "Webhook is not configured")
+		return
+	}
+
+	// The signature covers the exact bytes sent, so read the body before decoding it
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "Unable to read request body")
+		return
+	}
+
+	if !validWebhookSignature(secret, body, c.GetHeader(WebhookSignatureHeader)) {
+		common.RespondError(c, http.StatusUnauthorized, common.CodeUnauthorized, "Invalid webhook signature")
+		return
+	}
+
+	var event model.WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Invalid request", err.Error())
+		return
+	}
+	event.Status = strings.ToUpper(event.Status)
+	if event.Status != "COMPLETED" && event.Status != "FAILED" {
+		common.RespondErrorWithDetails(c, http.StatusBadRequest, common.CodeInvalidRequest, "Status must be COMPLETED or FAILED", gin.H{"status": event.Status})
+		return
+	}
+	if event.TransactionId == "" && event.Reference == "" {
+		common.RespondError(c, http.StatusBadRequest, common.CodeInvalidRequest, "transaction_id or reference is required")
+		return
+	}
+
+	db := database.GetDB()
+	tx := db.Begin()
+
+	// Lock the payment so a duplicate callback can't apply the same transition twice
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	if event.TransactionId != "" {
+		query = query.Where("gateway_transaction_id = ?", event.TransactionId)
+	} else {
+		query = query.Where("reference = ?", event.Reference)
+	}
+
+	var payment model.PaymentModel
+	if err := query.First(&payment).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.RespondError(c, http.StatusNotFound, common.CodeNotFound, "Payment not found")
+			return
+		}
+		log.Errorf("Failed to fetch payment for webhook: %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to fetch payment")
+		return
+	}
+
+	if payment.Status == event.Status {
+		tx.Rollback()
+		c.JSON(http.StatusOK, gin.H{
+			"message":    "Webhook already applied",
+			"idempotent": true,
+			"payment":    payment,
+		})
+		return
+	}
+
+	if payment.Status != "PROCESSING" {
+		tx.Rollback()
+		common.RespondErrorWithDetails(c, http.StatusConflict, common.CodeConflict, "Payment is no longer processing", gin.H{"status": payment.Status})
+		return
+	}
+
+	payment.Status = event.Status
+	if event.Status == "FAILED" {
+		payment.FailureReason = event.FailureReason
+	}
+	if err := tx.Save(&payment).Error; err != nil {
+		tx.Rollback()
+		log.Errorf("Failed to apply payment webhook: %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to update payment")
+		return
+	}
+
+	if payment.Status == "COMPLETED" {
+		if err := enqueuePaymentCompleted(tx, payment, common.RequestId(c)); err != nil {
+			tx.Rollback()
+			log.Errorf("Failed to record ship event for payment %d: %v", payment.PaymentId, err)
+			common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to update payment")
+			return
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Errorf("Failed to commit payment webhook: %v", err)
+		common.RespondError(c, http.StatusInternalServerError, common.CodeInternal, "Failed to update payment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook applied",
+		"payment": payment,
+	})
+}
+
+// validWebhookSignature compares the signature header against the body's HMAC in constant time
+func validWebhookSignature(secret string, body []byte, signature string) bool {
+	signature = strings.TrimPrefix(strings.TrimSpace(signature), "sha256=")
+	if signature == "" {
+		return false
+	}
+
+	received, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(received, mac.Sum(nil))
+}